@@ -0,0 +1,29 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// CacheStats returns the hit rate and size of the result cache of the logged in user
+func (h *Handler) CacheStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.instance.Auth == nil || !h.instance.Auth.IsUserLoggedIn() {
+		http.Error(w, "User signed out, Please Login!", http.StatusUnauthorized)
+		return
+	}
+	if h.instance.Cache == nil {
+		http.Error(w, "Result cache is not enabled", http.StatusNotFound)
+		return
+	}
+	responseJSON, err := json.MarshalIndent(h.instance.Cache.Stats(), "", "    ")
+	if err != nil {
+		http.Error(w, "Could marshal response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
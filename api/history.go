@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+)
+
+// History lists recorded queries of the logged in user, newest first. Query parameters since and
+// until (RFC3339), status (success or error) and limit filter the entries.
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.instance.Auth == nil || !h.instance.Auth.IsUserLoggedIn() {
+		http.Error(w, "User signed out, Please Login!", http.StatusUnauthorized)
+		return
+	}
+	store := boilingdata.History()
+	if store == nil {
+		http.Error(w, "Query history is not enabled", http.StatusNotFound)
+		return
+	}
+	params := r.URL.Query()
+	filter := boilingdata.HistoryFilter{User: h.instance.Auth.UserName(), Status: params.Get("status")}
+	if filter.Status != "" && filter.Status != boilingdata.HistoryStatusSuccess && filter.Status != boilingdata.HistoryStatusError {
+		http.Error(w, "status must be success or error", http.StatusBadRequest)
+		return
+	}
+	var err error
+	if filter.Since, err = timeParam(params.Get("since")); err != nil {
+		http.Error(w, "invalid since : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = timeParam(params.Get("until")); err != nil {
+		http.Error(w, "invalid until : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	entries, err := store.List(filter)
+	if err != nil {
+		http.Error(w, "Could not read query history : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	responseJSON, err := json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		http.Error(w, "Could marshal response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// RerunHistory runs the SQL of a recorded query again with the same tags and readCache
+func (h *Handler) RerunHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.instance.Auth == nil || !h.instance.Auth.IsUserLoggedIn() {
+		http.Error(w, "User signed out, Please Login!", http.StatusUnauthorized)
+		return
+	}
+	store := boilingdata.History()
+	if store == nil {
+		http.Error(w, "Query history is not enabled", http.StatusNotFound)
+		return
+	}
+	entry, ok, err := store.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Could not read query history : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok || entry.User != h.instance.Auth.UserName() {
+		http.Error(w, "No query with id "+r.PathValue("id")+" in history", http.StatusNotFound)
+		return
+	}
+	query := boilingdata.Query{SQL: entry.SQL, Tags: entry.Tags, ReadCache: entry.ReadCache}
+	query.CacheControl = cacheControl(r)
+	response, err := h.instance.Execute(r.Context(), query)
+	if err != nil {
+		http.Error(w, err.Error(), queryErrorStatus(err))
+		return
+	}
+	responseJSON, err := json.MarshalIndent(response, "", "    ")
+	if err != nil {
+		http.Error(w, "Could marshal response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+func timeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
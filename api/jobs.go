@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// defaultPageSize is the number of rows of a job result page when no limit is given
+const defaultPageSize = 1000
+
+type jobResultResponse struct {
+	*boilingdata.Result
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// Jobs starts running a query in the background, the body is the same as of /query. Returns the job with its id.
+func (h *Handler) Jobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jobs, ok := h.jobManager(w)
+	if !ok {
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Could not read http request body", http.StatusInternalServerError)
+		return
+	}
+	var payload models.Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "error unmarshalling Payload : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := queryFromPayload(payload)
+	query.CacheControl = cacheControl(r)
+	instance := h.instance
+	writeJSON(w, http.StatusAccepted, jobs.Submit(&instance, query))
+}
+
+// Job returns the status and progress of a job on GET. DELETE cancels a running job or removes a finished one.
+func (h *Handler) Job(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jobs, ok := h.jobManager(w)
+	if !ok {
+		return
+	}
+	job, ok := h.ownJob(w, r, jobs)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodDelete {
+		job, _ = jobs.Cancel(job.ID)
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// JobResult returns a page of the result of a finished job, ?offset= and ?limit= select the rows
+func (h *Handler) JobResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jobs, ok := h.jobManager(w)
+	if !ok {
+		return
+	}
+	if _, ok := h.ownJob(w, r, jobs); !ok {
+		return
+	}
+	offset, limit := 0, defaultPageSize
+	var err error
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	job, result, found := jobs.Result(r.PathValue("id"))
+	if !found {
+		// Expired or removed since it was looked up
+		http.Error(w, "No job "+r.PathValue("id"), http.StatusNotFound)
+		return
+	}
+	switch job.Status {
+	case boilingdata.JobRunning:
+		http.Error(w, "Job is still running", http.StatusConflict)
+		return
+	case boilingdata.JobCancelled:
+		http.Error(w, "Job was cancelled", http.StatusGone)
+		return
+	case boilingdata.JobFailed:
+		http.Error(w, job.Error, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobResultResponse{
+		Result: result.Page(offset, limit),
+		Total:  len(result.Rows),
+		Offset: offset,
+		Limit:  limit,
+	})
+}
+
+// jobManager checks the user is logged in and jobs are enabled
+func (h *Handler) jobManager(w http.ResponseWriter) (*boilingdata.JobManager, bool) {
+	if h.instance.Auth == nil || !h.instance.Auth.IsUserLoggedIn() {
+		http.Error(w, "User signed out, Please Login!", http.StatusUnauthorized)
+		return nil, false
+	}
+	jobs := boilingdata.GetJobManager()
+	if jobs == nil {
+		http.Error(w, "Jobs are not enabled", http.StatusNotFound)
+		return nil, false
+	}
+	return jobs, true
+}
+
+// ownJob returns the job of the path, jobs of other users are not found
+func (h *Handler) ownJob(w http.ResponseWriter, r *http.Request, jobs *boilingdata.JobManager) (boilingdata.Job, bool) {
+	id := r.PathValue("id")
+	job, found := jobs.Get(id)
+	if !found || job.User != h.instance.Auth.UserName() {
+		http.Error(w, "No job "+id, http.StatusNotFound)
+		return boilingdata.Job{}, false
+	}
+	return job, true
+}
@@ -3,7 +3,10 @@ package api
 import (
 	"encoding/json"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/pavi6691/go-boilingdata/boilingdata"
 )
@@ -13,6 +16,9 @@ type Credentials struct {
 	Password string `json:"password"`
 }
 
+// Failed logins per username and per client ip before they get locked out
+var loginLimiter = boilingdata.NewLoginLimiter(5, 30*time.Second, 30*time.Minute)
+
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	// Check if the request method is POST
 	if r.Method != http.MethodPost {
@@ -34,12 +40,58 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to parse JSON: %v", http.StatusInternalServerError)
 		return
 	}
-	instance := boilingdata.GetInstance(creds.UserName, creds.Password)
-	_, err = instance.Auth.Authenticate()
+	sourceIP := clientIP(r)
+	userKey := "user:" + creds.UserName
+	ipKey := "ip:" + sourceIP
+	if ok, wait := loginLimiter.Allow(userKey, ipKey); !ok {
+		boilingdata.RecordLoginLockout(creds.UserName, sourceIP)
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+	instance, err := boilingdata.Login(creds.UserName, creds.Password, sourceIP)
 	if err != nil {
+		loginLimiter.Failure(userKey)
+		loginLimiter.Failure(ipKey)
 		http.Error(w, "Error : "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// A successful login only clears the user, a client could otherwise reset its failures by
+	// logging into its own account between attempts on other accounts
+	loginLimiter.Success(userKey)
+	loginLimiter.Release(ipKey)
 	h.instance = *instance
 	w.Write([]byte("Login Successful!"))
 }
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.instance.Auth == nil || !h.instance.Auth.IsUserLoggedIn() {
+		http.Error(w, "User signed out, Please Login!", http.StatusUnauthorized)
+		return
+	}
+	boilingdata.Logout(h.instance.Auth.UserName())
+	h.instance = boilingdata.Instance{}
+	w.Write([]byte("Logged out!"))
+}
+
+// LoginWithProvider logs in with credentials from the provider, used to start the server already authenticated
+func (h *Handler) LoginWithProvider(provider boilingdata.CredentialProvider) error {
+	instance, err := boilingdata.LoginWithProvider(provider)
+	if err != nil {
+		return err
+	}
+	h.instance = *instance
+	return nil
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -2,10 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/pavi6691/go-boilingdata/boilingdata"
+	"github.com/pavi6691/go-boilingdata/models"
 )
 
 type Handler struct {
@@ -30,11 +33,44 @@ func (h *Handler) Query(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.instance.Query(body)
+	var request queryRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		http.Error(w, "error unmarshalling Payload : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.PageSize < 0 {
+		http.Error(w, "pageSize can not be negative", http.StatusBadRequest)
+		return
+	}
+	if request.Cursor != "" {
+		h.nextPage(w, request)
+		return
+	}
+	query := queryFromPayload(request.Payload)
+	query.CacheControl = cacheControl(r)
+	result, err := h.instance.Execute(r.Context(), query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), queryErrorStatus(err))
 		return
 	}
+	// The websocket response unless the result with its meta is asked for
+	var response interface{} = result.Response()
+	if r.URL.Query().Get("meta") == "true" {
+		response = result
+	}
+	if request.PageSize > 0 {
+		cursors := boilingdata.GetCursorStore()
+		if cursors == nil {
+			http.Error(w, "Paging is not enabled", http.StatusNotFound)
+			return
+		}
+		page, err := cursors.FirstPage(h.instance.Auth.UserName(), result, request.PageSize)
+		if err != nil {
+			http.Error(w, "Could not keep result for paging : "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response = page
+	}
 	// Set response content type to JSON
 	w.Header().Set("Content-Type", "application/json")
 	// Write JSON response to the response body
@@ -46,3 +82,59 @@ func (h *Handler) Query(w http.ResponseWriter, r *http.Request) {
 	w.Write(responseJSON)
 
 }
+
+// queryRequest is the body of /query, pageSize pages the result and cursor reads the next page
+type queryRequest struct {
+	models.Payload
+	PageSize int    `json:"pageSize"`
+	Cursor   string `json:"cursor"`
+}
+
+// nextPage writes the page of the cursor of the request
+func (h *Handler) nextPage(w http.ResponseWriter, request queryRequest) {
+	cursors := boilingdata.GetCursorStore()
+	if cursors == nil {
+		http.Error(w, "Paging is not enabled", http.StatusNotFound)
+		return
+	}
+	page, err := cursors.Next(h.instance.Auth.UserName(), request.Cursor, request.PageSize)
+	if errors.Is(err, boilingdata.ErrCursorNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not read page : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func queryFromPayload(payload models.Payload) boilingdata.Query {
+	return boilingdata.Query{
+		SQL:       payload.SQL,
+		Tags:      payload.Tags,
+		ReadCache: payload.ReadCache,
+		RequestID: payload.RequestID,
+	}
+}
+
+// cacheControl maps the Cache-Control header of the request to the use of the result cache,
+// no-store bypasses the cache and no-cache refreshes the cached result
+func cacheControl(r *http.Request) boilingdata.CacheControl {
+	header := strings.ToLower(r.Header.Get("Cache-Control"))
+	switch {
+	case strings.Contains(header, "no-store"):
+		return boilingdata.CacheBypass
+	case strings.Contains(header, "no-cache"):
+		return boilingdata.CacheRefresh
+	}
+	return boilingdata.CacheUse
+}
+
+// queryErrorStatus returns 429 when BoilingData throttled the query after all retries, 500 otherwise
+func queryErrorStatus(err error) int {
+	if errors.Is(err, boilingdata.ErrThrottled) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
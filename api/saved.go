@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+)
+
+// SavedQueries lists the saved queries visible to the user on GET and saves a query on POST.
+// Names are per user, saving a query with a name the user already used adds a new version.
+func (h *Handler) SavedQueries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	store, ok := h.savedQueryStore(w)
+	if !ok {
+		return
+	}
+	user := h.instance.Auth.UserName()
+	if r.Method == http.MethodGet {
+		queries, err := store.List()
+		if err != nil {
+			http.Error(w, "Could not read saved queries : "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		visible := []boilingdata.SavedQuery{}
+		for _, q := range queries {
+			if q.VisibleTo(user) {
+				visible = append(visible, q)
+			}
+		}
+		writeJSON(w, http.StatusOK, visible)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Could not read http request body", http.StatusInternalServerError)
+		return
+	}
+	var query boilingdata.SavedQuery
+	if err := decodeJSON(body, &query); err != nil {
+		http.Error(w, "error unmarshalling saved query : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.Owner = user
+	if query.Visibility == "" {
+		query.Visibility = boilingdata.VisibilityPrivate
+	}
+	if err := query.Validate(); err != nil {
+		http.Error(w, "invalid saved query : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	saved, err := store.Save(query)
+	if err != nil {
+		http.Error(w, "Could not save query : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, saved)
+}
+
+// SavedQuery returns the latest or the ?version= of a saved query on GET and deletes all versions of
+// the user's own query on DELETE
+func (h *Handler) SavedQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	store, ok := h.savedQueryStore(w)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodGet {
+		if query, ok := h.visibleSavedQuery(w, r, store); ok {
+			writeJSON(w, http.StatusOK, query)
+		}
+		return
+	}
+	user, name := h.instance.Auth.UserName(), r.PathValue("name")
+	_, found, err := store.Get(user, name, 0)
+	if err != nil {
+		http.Error(w, "Could not read saved queries : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "No saved query "+name, http.StatusNotFound)
+		return
+	}
+	if err := store.Delete(user, name); err != nil {
+		http.Error(w, "Could not delete saved query : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SavedQueryVersions returns the versions of a saved query visible to the user, oldest first
+func (h *Handler) SavedQueryVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	store, ok := h.savedQueryStore(w)
+	if !ok {
+		return
+	}
+	query, ok := h.visibleSavedQuery(w, r, store)
+	if !ok {
+		return
+	}
+	versions, err := store.Versions(query.Owner, query.Name)
+	if err != nil {
+		http.Error(w, "Could not read saved queries : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Versions saved before the query was shared stay private
+	visible := []boilingdata.SavedQuery{}
+	for _, version := range versions {
+		if version.VisibleTo(h.instance.Auth.UserName()) {
+			visible = append(visible, version)
+		}
+	}
+	writeJSON(w, http.StatusOK, visible)
+}
+
+// RunSavedQuery runs the latest or the ?version= of a saved query, the body is a JSON object of its parameters
+func (h *Handler) RunSavedQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	store, ok := h.savedQueryStore(w)
+	if !ok {
+		return
+	}
+	saved, ok := h.visibleSavedQuery(w, r, store)
+	if !ok {
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Could not read http request body", http.StatusInternalServerError)
+		return
+	}
+	params := map[string]interface{}{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := decodeJSON(body, &params); err != nil {
+			http.Error(w, "error unmarshalling parameters : "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	query, err := saved.Query(params)
+	if err != nil {
+		http.Error(w, "invalid parameters : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.CacheControl = cacheControl(r)
+	response, err := h.instance.Execute(r.Context(), query)
+	if err != nil {
+		http.Error(w, err.Error(), queryErrorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// savedQueryStore checks the user is logged in and saved queries are enabled
+func (h *Handler) savedQueryStore(w http.ResponseWriter) (boilingdata.SavedQueryStore, bool) {
+	if h.instance.Auth == nil || !h.instance.Auth.IsUserLoggedIn() {
+		http.Error(w, "User signed out, Please Login!", http.StatusUnauthorized)
+		return nil, false
+	}
+	store := boilingdata.SavedQueries()
+	if store == nil {
+		http.Error(w, "Saved queries are not enabled", http.StatusNotFound)
+		return nil, false
+	}
+	return store, true
+}
+
+// visibleSavedQuery returns the saved query of the path, the user's own one or, with ?owner= or when the user
+// has none, the one another user shared. Queries of other users that are not shared are not found.
+func (h *Handler) visibleSavedQuery(w http.ResponseWriter, r *http.Request, store boilingdata.SavedQueryStore) (boilingdata.SavedQuery, bool) {
+	name := r.PathValue("name")
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil || version < 1 {
+			http.Error(w, "invalid version", http.StatusBadRequest)
+			return boilingdata.SavedQuery{}, false
+		}
+	}
+	query, found, err := boilingdata.ResolveSavedQuery(store, h.instance.Auth.UserName(), r.URL.Query().Get("owner"), name, version)
+	if errors.Is(err, boilingdata.ErrAmbiguousSavedQuery) {
+		http.Error(w, "Several users shared a saved query "+name+", set ?owner=", http.StatusConflict)
+		return boilingdata.SavedQuery{}, false
+	}
+	if err != nil {
+		http.Error(w, "Could not read saved queries : "+err.Error(), http.StatusInternalServerError)
+		return boilingdata.SavedQuery{}, false
+	}
+	if !found {
+		http.Error(w, "No saved query "+name, http.StatusNotFound)
+		return boilingdata.SavedQuery{}, false
+	}
+	return query, true
+}
+
+// decodeJSON unmarshals keeping numbers as json.Number, so they are bound without losing precision
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	responseJSON, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		http.Error(w, "Could marshal response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(responseJSON)
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+)
+
+type scheduleResponse struct {
+	boilingdata.Schedule
+	NextRun *time.Time `json:"nextRun,omitempty"`
+}
+
+func newScheduleResponse(schedule boilingdata.Schedule) scheduleResponse {
+	response := scheduleResponse{Schedule: schedule}
+	if next := schedule.NextRun(time.Now()); !next.IsZero() {
+		response.NextRun = &next
+	}
+	return response
+}
+
+// Schedules lists the schedules of the user on GET and creates a schedule on POST
+func (h *Handler) Schedules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scheduler, ok := h.scheduler(w)
+	if !ok {
+		return
+	}
+	user := h.instance.Auth.UserName()
+	if r.Method == http.MethodGet {
+		schedules, err := scheduler.Store().List()
+		if err != nil {
+			http.Error(w, "Could not read schedules : "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		owned := []scheduleResponse{}
+		for _, schedule := range schedules {
+			if schedule.Owner == user {
+				owned = append(owned, newScheduleResponse(schedule))
+			}
+		}
+		writeJSON(w, http.StatusOK, owned)
+		return
+	}
+	schedule, ok := h.readSchedule(w, r)
+	if !ok {
+		return
+	}
+	schedule.Created = time.Now().UTC()
+	schedule.Updated = schedule.Created
+	schedule, err := scheduler.Store().Save(schedule)
+	if err != nil {
+		http.Error(w, "Could not save schedule : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newScheduleResponse(schedule))
+}
+
+// Schedule returns a schedule on GET, replaces it on PUT and deletes it with its result files on DELETE
+func (h *Handler) Schedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scheduler, ok := h.scheduler(w)
+	if !ok {
+		return
+	}
+	existing, ok := h.ownSchedule(w, r, scheduler)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, newScheduleResponse(existing))
+	case http.MethodPut:
+		schedule, ok := h.readSchedule(w, r)
+		if !ok {
+			return
+		}
+		schedule.ID = existing.ID
+		schedule.Created = existing.Created
+		schedule.Updated = time.Now().UTC()
+		schedule, err := scheduler.Store().Save(schedule)
+		if err != nil {
+			http.Error(w, "Could not save schedule : "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, newScheduleResponse(schedule))
+	case http.MethodDelete:
+		if err := scheduler.Store().Delete(existing.ID); err != nil {
+			http.Error(w, "Could not delete schedule : "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := scheduler.RemoveSnapshots(existing.ID); err != nil {
+			http.Error(w, "Could not delete result files : "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RunSchedule runs a schedule now and returns the run
+func (h *Handler) RunSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scheduler, ok := h.scheduler(w)
+	if !ok {
+		return
+	}
+	schedule, ok := h.ownSchedule(w, r, scheduler)
+	if !ok {
+		return
+	}
+	run, err := scheduler.Run(schedule.ID, boilingdata.TriggerManual)
+	if err != nil && run.ID == "" {
+		// The schedule did not run at all, e.g. it is running already
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// ScheduleRuns returns the last runs of a schedule, newest first
+func (h *Handler) ScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scheduler, ok := h.scheduler(w)
+	if !ok {
+		return
+	}
+	schedule, ok := h.ownSchedule(w, r, scheduler)
+	if !ok {
+		return
+	}
+	runs, err := scheduler.Store().Runs(schedule.ID)
+	if err != nil {
+		http.Error(w, "Could not read schedule runs : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// scheduler checks the user is logged in and schedules are enabled
+func (h *Handler) scheduler(w http.ResponseWriter) (*boilingdata.Scheduler, bool) {
+	if h.instance.Auth == nil || !h.instance.Auth.IsUserLoggedIn() {
+		http.Error(w, "User signed out, Please Login!", http.StatusUnauthorized)
+		return nil, false
+	}
+	scheduler := boilingdata.GetScheduler()
+	if scheduler == nil {
+		http.Error(w, "Schedules are not enabled", http.StatusNotFound)
+		return nil, false
+	}
+	return scheduler, true
+}
+
+// ownSchedule returns the schedule of the path, schedules of other users are not found
+func (h *Handler) ownSchedule(w http.ResponseWriter, r *http.Request, scheduler *boilingdata.Scheduler) (boilingdata.Schedule, bool) {
+	id := r.PathValue("id")
+	schedule, found, err := scheduler.Store().Get(id)
+	if err != nil {
+		http.Error(w, "Could not read schedules : "+err.Error(), http.StatusInternalServerError)
+		return boilingdata.Schedule{}, false
+	}
+	if !found || schedule.Owner != h.instance.Auth.UserName() {
+		http.Error(w, "No schedule "+id, http.StatusNotFound)
+		return boilingdata.Schedule{}, false
+	}
+	return schedule, true
+}
+
+// readSchedule reads and validates the schedule of the body, which must use a credential of the user
+func (h *Handler) readSchedule(w http.ResponseWriter, r *http.Request) (boilingdata.Schedule, bool) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Could not read http request body", http.StatusInternalServerError)
+		return boilingdata.Schedule{}, false
+	}
+	schedule := boilingdata.Schedule{Enabled: true, Format: boilingdata.FormatJSON}
+	if err := decodeJSON(body, &schedule); err != nil {
+		http.Error(w, "error unmarshalling schedule : "+err.Error(), http.StatusBadRequest)
+		return boilingdata.Schedule{}, false
+	}
+	// Ids are generated, never taken from the body
+	schedule.ID = ""
+	schedule.Owner = h.instance.Auth.UserName()
+	if err := schedule.Validate(); err != nil {
+		http.Error(w, "invalid schedule : "+err.Error(), http.StatusBadRequest)
+		return boilingdata.Schedule{}, false
+	}
+	provider, _ := schedule.Credential.Provider()
+	creds, err := provider.Retrieve()
+	if err != nil {
+		http.Error(w, "invalid schedule : could not retrieve credential : "+err.Error(), http.StatusBadRequest)
+		return boilingdata.Schedule{}, false
+	}
+	if creds.UserName != schedule.Owner {
+		http.Error(w, "invalid schedule : credential is not of the logged in user", http.StatusForbidden)
+		return boilingdata.Schedule{}, false
+	}
+	return schedule, true
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// streamEvent is an event of /query/stream, only the field of its type is set
+type streamEvent struct {
+	Type     string                   `json:"type"`
+	Schema   *models.Schema           `json:"schema,omitempty"`
+	Rows     []map[string]interface{} `json:"rows,omitempty"`
+	Progress *boilingdata.Progress    `json:"progress,omitempty"`
+	Meta     *boilingdata.ResultMeta  `json:"meta,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+	Status   int                      `json:"status,omitempty"`
+}
+
+// eventWriter writes events as newline delimited JSON or as server sent events, flushing every event
+type eventWriter struct {
+	w   http.ResponseWriter
+	sse bool
+}
+
+func (e *eventWriter) write(event streamEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Println("Could not marshal stream event: " + err.Error())
+		return
+	}
+	if e.sse {
+		data = []byte("event: " + event.Type + "\ndata: " + string(data) + "\n\n")
+	} else {
+		data = append(data, '\n')
+	}
+	if _, err := e.w.Write(data); err != nil {
+		return
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// QueryStream runs a query like /query and streams its result as the parts of the response arrive.
+// Accept: text/event-stream streams server sent events, anything else newline delimited JSON. The stream
+// starts with a schema event, then rows and progress events follow and it ends with a stats or error event.
+func (h *Handler) QueryStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.instance.Auth == nil || !h.instance.Auth.IsUserLoggedIn() {
+		http.Error(w, "User signed out, Please Login!", http.StatusUnauthorized)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Could not read http request body", http.StatusInternalServerError)
+		return
+	}
+	var payload models.Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "error unmarshalling Payload : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := queryFromPayload(payload)
+	query.CacheControl = cacheControl(r)
+
+	events := &eventWriter{w: w, sse: strings.Contains(r.Header.Get("Accept"), "text/event-stream")}
+	if events.sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	result, err := h.instance.Stream(r.Context(), query, boilingdata.StreamHandler{
+		Schema: func(schema *models.Schema) {
+			events.write(streamEvent{Type: "schema", Schema: schema})
+		},
+		Rows: func(rows []map[string]interface{}) {
+			events.write(streamEvent{Type: "rows", Rows: rows})
+		},
+		Progress: func(progress boilingdata.Progress) {
+			events.write(streamEvent{Type: "progress", Progress: &progress})
+		},
+	})
+	if err != nil {
+		events.write(streamEvent{Type: "error", Error: err.Error(), Status: queryErrorStatus(err)})
+		return
+	}
+	events.write(streamEvent{Type: "stats", Schema: result.Schema, Meta: &result.Meta})
+}
@@ -0,0 +1,159 @@
+package boilingdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+const (
+	AuthFlowPassword = "USER_PASSWORD_AUTH"
+	AuthFlowRefresh  = "REFRESH_TOKEN_AUTH"
+	AuthFlowIAM      = "IAM"
+	AuthFlowLogout   = "LOGOUT"
+	OutcomeSuccess   = "success"
+	OutcomeFailure   = "failure"
+	OutcomeLockout   = "lockout"
+)
+
+// AuthEvent is a structured record of a login, refresh, failure or logout
+type AuthEvent struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	SourceIP  string    `json:"sourceIp,omitempty"`
+	Flow      string    `json:"flow"`
+	Outcome   string    `json:"outcome"`
+	ErrorCode string    `json:"errorCode,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditSink receives auth events, it must be safe for concurrent use
+type AuditSink interface {
+	Emit(event AuthEvent)
+}
+
+var auditMu sync.RWMutex
+var auditSink AuditSink
+
+// SetAuditSink sets where auth events are sent, nil disables auditing
+func SetAuditSink(sink AuditSink) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditSink = sink
+}
+
+func emitAuthEvent(user string, sourceIP string, flow string, outcome string, err error) {
+	auditMu.RLock()
+	sink := auditSink
+	auditMu.RUnlock()
+	if sink == nil {
+		return
+	}
+	event := AuthEvent{Time: time.Now().UTC(), User: user, SourceIP: sourceIP, Flow: flow, Outcome: outcome}
+	if err != nil {
+		event.Error = err.Error()
+		if awsErr, ok := err.(awserr.Error); ok {
+			event.ErrorCode = awsErr.Code()
+		}
+	}
+	sink.Emit(event)
+}
+
+// RecordLoginLockout records a login rejected because the user or the client is locked out
+func RecordLoginLockout(userName string, sourceIP string) {
+	countLoginLockout()
+	emitAuthEvent(userName, sourceIP, AuthFlowPassword, OutcomeLockout, nil)
+}
+
+// JSONLinesSink writes auth events as JSON lines to a file, rotating it once it grows beyond maxBytes.
+// Rotated files are named file.1 (newest) to file.maxBackups (oldest).
+type JSONLinesSink struct {
+	filename   string
+	maxBytes   int64
+	maxBackups int
+	mu         sync.Mutex
+	file       *os.File
+	size       int64
+}
+
+func NewJSONLinesSink(filename string, maxBytes int64, maxBackups int) (*JSONLinesSink, error) {
+	sink := &JSONLinesSink{filename: filename, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (s *JSONLinesSink) Emit(event AuthEvent) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		log.Println("Could not marshal auth event: " + err.Error())
+		return
+	}
+	line = append(line, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxBytes > 0 && s.size+int64(len(line)) > s.maxBytes && s.size > 0 {
+		if err := s.rotate(); err != nil {
+			log.Println("Could not rotate audit log: " + err.Error())
+		}
+	}
+	if s.file == nil {
+		return
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		log.Println("Could not write audit log: " + err.Error())
+	}
+}
+
+func (s *JSONLinesSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *JSONLinesSink) open() error {
+	file, err := os.OpenFile(s.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *JSONLinesSink) rotate() error {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	if s.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", s.filename, s.maxBackups))
+		for i := s.maxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", s.filename, i), fmt.Sprintf("%s.%d", s.filename, i+1))
+		}
+		if err := os.Rename(s.filename, s.filename+".1"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if err := os.Remove(s.filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.open()
+}
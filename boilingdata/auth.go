@@ -17,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
 	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/pavi6691/go-boilingdata/constants"
 )
 
@@ -25,6 +26,7 @@ type AwsCredentials struct {
 	SecretAccessKey string
 	SessionToken    string
 	CredentialScope string
+	Expiration      time.Time
 }
 
 type Auth struct {
@@ -32,14 +34,47 @@ type Auth struct {
 	password                        string
 	authResult                      *cognitoidentityprovider.AuthenticationResultType
 	timeWhenLastJwtTokenWasRecieved time.Time
+	// iamCredentials are set in IAM mode, the websocket request is then signed with them directly
+	// and the user pool and identity pool are not used
+	iamCredentials *credentials.Credentials
+	// expiry of the AWS credentials last used to sign the websocket request
+	awsCredentialsExpiration time.Time
+	// client ip the user logged in from, recorded in auth events
+	sourceIP string
+	// endpoints to use, DefaultConfig when nil
+	config *Config
+}
+
+func (auth *Auth) conf() Config {
+	if auth.config == nil {
+		return DefaultConfig()
+	}
+	return *auth.config
+}
+
+// NewIAMAuth creates an Auth that signs the websocket request with the given AWS credentials, e.g.
+// credentials.NewStaticCredentials, credentials.NewEnvCredentials or credentials.NewSharedCredentials
+func NewIAMAuth(name string, iamCredentials *credentials.Credentials) *Auth {
+	return &Auth{userName: name, iamCredentials: iamCredentials}
+}
+
+func (auth *Auth) IsIAMMode() bool {
+	return auth.iamCredentials != nil
 }
 
 func (s *Auth) GetSignedWssHeader(token string) (http.Header, error) {
-	creds, err := GetAwsCredentialss(token)
-	if err != nil {
-		return nil, err
+	var creds *credentials.Credentials
+	if s.IsIAMMode() {
+		creds = s.iamCredentials
+	} else {
+		awsCreds, err := getAwsCredentials(s.conf(), token)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewStaticCredentials(awsCreds.AccessKeyId, awsCreds.SecretAccessKey, awsCreds.SessionToken)
+		s.awsCredentialsExpiration = awsCreds.Expiration
 	}
-	header, err := getSignedHeaders(creds)
+	header, err := getSignedHeaders(s.conf(), creds)
 	if err != nil {
 		log.Printf("Error getting singned url headers: " + err.Error())
 		return nil, err
@@ -48,13 +83,21 @@ func (s *Auth) GetSignedWssHeader(token string) (http.Header, error) {
 }
 
 func (s *Auth) GetSignedWssUrl(headers http.Header) (string, error) {
-	credential, signature, err := extractCredentialAndSignature(headers["Authorization"][0])
+	credential, signature, err := extractCredentialAndSignature(headers.Get("Authorization"))
 	if err != nil {
 		log.Printf("Error Extracting Credential and Signature: " + err.Error())
 		return "", err
 	}
-	signedUrl := constants.WssUrl + "?" + fmt.Sprintf(constants.SignWrlFormat, url.QueryEscape(credential)+"&",
-		url.QueryEscape(headers["X-Amz-Date"][0])+"&", url.QueryEscape(headers["X-Amz-Security-Token"][0])+"&", url.QueryEscape(signature))
+	format := constants.SignWrlFormat
+	args := []interface{}{url.QueryEscape(credential) + "&", url.QueryEscape(headers.Get("X-Amz-Date")) + "&"}
+	if token := headers.Get("X-Amz-Security-Token"); token != "" {
+		args = append(args, url.QueryEscape(token)+"&")
+	} else {
+		// Static IAM credentials have no session token
+		format = strings.Replace(format, "X-Amz-Security-Token=%s", "", 1)
+	}
+	args = append(args, url.QueryEscape(signature))
+	signedUrl := s.conf().WssURL + "?" + fmt.Sprintf(format, args...)
 	return signedUrl, nil
 }
 
@@ -80,15 +123,19 @@ func extractCredentialAndSignature(header string) (string, string, error) {
 }
 
 func GetAwsCredentialss(jwtIdToken string) (AwsCredentials, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(constants.Region))
+	return getAwsCredentials(DefaultConfig(), jwtIdToken)
+}
+
+func getAwsCredentials(bdConfig Config, jwtIdToken string) (AwsCredentials, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(bdConfig.Region))
 	if err != nil {
 		return AwsCredentials{}, fmt.Errorf("failed to load configuration, %v", err)
 	}
 	cognitoClient := cognitoidentity.NewFromConfig(cfg)
 
 	out, err := cognitoClient.GetId(context.TODO(), &cognitoidentity.GetIdInput{
-		IdentityPoolId: aws.String(constants.IdentityPoolId),
-		Logins:         map[string]string{constants.CognitoIdp: jwtIdToken},
+		IdentityPoolId: aws.String(bdConfig.IdentityPoolID),
+		Logins:         map[string]string{bdConfig.cognitoIdp(): jwtIdToken},
 	})
 
 	if err != nil {
@@ -100,7 +147,7 @@ func GetAwsCredentialss(jwtIdToken string) (AwsCredentials, error) {
 	credRes, err := cognitoClient.GetCredentialsForIdentity(ctx, &cognitoidentity.GetCredentialsForIdentityInput{
 		IdentityId: out.IdentityId,
 		Logins: map[string]string{
-			constants.CognitoIdp: jwtIdToken,
+			bdConfig.cognitoIdp(): jwtIdToken,
 		},
 	})
 
@@ -114,6 +161,9 @@ func GetAwsCredentialss(jwtIdToken string) (AwsCredentials, error) {
 		SecretAccessKey: *credRes.Credentials.SecretKey,
 		SessionToken:    *credRes.Credentials.SessionToken,
 	}
+	if credRes.Credentials.Expiration != nil {
+		awsCreds.Expiration = *credRes.Credentials.Expiration
+	}
 
 	return awsCreds, nil
 }
@@ -158,19 +208,19 @@ func (auth *Auth) GetAWSSingingHeaders(urlString string) (http.Header, error) {
 	return headers, nil
 }
 
-func getSignedHeaders(creds AwsCredentials) (http.Header, error) {
+func getSignedHeaders(cfg Config, creds *credentials.Credentials) (http.Header, error) {
 	// Create a signer with the given AWS credentials
-	signer := v4.NewSigner(credentials.NewStaticCredentials(creds.AccessKeyId, creds.SecretAccessKey, creds.SessionToken))
-	wsURL := constants.WssUrl
+	signer := v4.NewSigner(creds)
+	wsURL := cfg.WssURL
 	req, err := http.NewRequest("GET", wsURL, nil)
 	if err != nil {
 		return nil, err
 	}
 	// Sign the request
-	_, err = signer.Sign(req, nil, constants.Service, constants.Region, time.Now())
+	_, err = signer.Sign(req, nil, constants.Service, cfg.Region, time.Now())
 	if err != nil {
 		log.Println("Error signing request:", err)
-		return nil, nil
+		return nil, err
 	}
 	// Return the signed URL
 	return req.Header, err
@@ -180,37 +230,51 @@ func (auth *Auth) Authenticate() (string, error) {
 	muLock.Lock()
 	defer muLock.Unlock()
 
+	if auth.IsIAMMode() {
+		// No jwt token in IAM mode, only check the credentials can be resolved
+		if _, err := auth.iamCredentials.Get(); err != nil {
+			log.Println("Could not get AWS credentials, ->" + err.Error())
+			auth.emitEvent(AuthFlowIAM, OutcomeFailure, err)
+			return "", err
+		}
+		auth.emitEvent(AuthFlowIAM, OutcomeSuccess, nil)
+		return "", nil
+	}
+
+	cfg := auth.conf()
 	var authInput *cognitoidentityprovider.InitiateAuthInput
 	if auth.IsUserLoggedIn() && !auth.IsTokenExpired() {
 		return *auth.authResult.IdToken, nil
 	} else if auth.IsUserLoggedIn() || auth.password == "" {
 		log.Println("Token expired, Getting token with refresh token..")
 		authInput = &cognitoidentityprovider.InitiateAuthInput{
-			AuthFlow: aws.String("REFRESH_TOKEN_AUTH"),
+			AuthFlow: aws.String(AuthFlowRefresh),
 			AuthParameters: map[string]*string{
 				"REFRESH_TOKEN": aws.String(*auth.authResult.RefreshToken),
-				"POOL_ID":       aws.String(constants.PoolID),
+				"POOL_ID":       aws.String(cfg.UserPoolID),
 			},
-			ClientId: aws.String(constants.ClientID),
+			ClientId: aws.String(cfg.ClientID),
 		}
 	} else {
 		log.Println("Logging in..")
 		// Authenticate user
 		authInput = &cognitoidentityprovider.InitiateAuthInput{
-			AuthFlow: aws.String("USER_PASSWORD_AUTH"),
+			AuthFlow: aws.String(AuthFlowPassword),
 			AuthParameters: map[string]*string{
 				"USERNAME": aws.String(auth.userName),
 				"PASSWORD": aws.String(auth.password),
-				"POOL_ID":  aws.String(constants.PoolID),
+				"POOL_ID":  aws.String(cfg.UserPoolID),
 			},
-			ClientId: aws.String(constants.ClientID),
+			ClientId: aws.String(cfg.ClientID),
 		}
 	}
+	flow := *authInput.AuthFlow
 	//
 	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(constants.Region)},
+		Region: aws.String(cfg.Region)},
 	)
 	if err != nil {
+		auth.emitEvent(flow, OutcomeFailure, err)
 		return "", err
 	}
 	cognitoClient := cognitoidentityprovider.New(sess)
@@ -218,8 +282,9 @@ func (auth *Auth) Authenticate() (string, error) {
 	//
 	if err != nil {
 		log.Println("Login unsucessful, ->" + err.Error())
+		auth.emitEvent(flow, OutcomeFailure, err)
 		auth.authResult = nil
-		RemoveUser(auth.userName)
+		auth.removeIfRegistered()
 		return "", err
 	}
 	auth.timeWhenLastJwtTokenWasRecieved = time.Now()
@@ -230,23 +295,27 @@ func (auth *Auth) Authenticate() (string, error) {
 		case "SMS_MFA":
 			mfaCode, err := promptMFA("Please enter MFA (sms)")
 			if err != nil {
-				RemoveUser(auth.userName)
+				auth.emitEvent(flow, OutcomeFailure, err)
+				auth.removeIfRegistered()
 				return "", err
 			}
 			err = sendMFA(cognitoClient, authOutput.Session, mfaCode, "SMS_MFA")
 			if err != nil {
-				RemoveUser(auth.userName)
+				auth.emitEvent(flow, OutcomeFailure, err)
+				auth.removeIfRegistered()
 				return "", err
 			}
 		case "SOFTWARE_TOKEN_MFA":
 			mfaCode, err := promptMFA("Please enter MFA (totp)")
 			if err != nil {
-				RemoveUser(auth.userName)
+				auth.emitEvent(flow, OutcomeFailure, err)
+				auth.removeIfRegistered()
 				return "", err
 			}
 			err = sendMFA(cognitoClient, authOutput.Session, mfaCode, "SOFTWARE_TOKEN_MFA")
 			if err != nil {
-				RemoveUser(auth.userName)
+				auth.emitEvent(flow, OutcomeFailure, err)
+				auth.removeIfRegistered()
 				return "", err
 			}
 		}
@@ -256,7 +325,8 @@ func (auth *Auth) Authenticate() (string, error) {
 	if authOutput.AuthenticationResult == nil {
 		newPassword, err := promptPassword("Please enter new password")
 		if err != nil {
-			RemoveUser(auth.userName)
+			auth.emitEvent(flow, OutcomeFailure, err)
+			auth.removeIfRegistered()
 			return "", err
 		}
 		// Assume, need to provide new password
@@ -265,16 +335,36 @@ func (auth *Auth) Authenticate() (string, error) {
 	}
 	// Authentication successful
 	log.Println("Authentication successful")
+	auth.emitEvent(flow, OutcomeSuccess, nil)
 	return *authOutput.AuthenticationResult.IdToken, nil
 }
 
+func (auth *Auth) emitEvent(flow string, outcome string, err error) {
+	emitAuthEvent(auth.userName, auth.sourceIP, flow, outcome, err)
+}
+
+// removeIfRegistered removes the user only when this Auth belongs to the registered instance,
+// so a failed login attempt does not tear down a valid session of the same user
+func (auth *Auth) removeIfRegistered() {
+	if qs, ok := queryServiceMap.Get(auth.userName); ok && qs.(*Instance).Auth == auth {
+		RemoveUser(auth.userName)
+	}
+}
+
 func (auth *Auth) IsUserLoggedIn() bool {
+	if auth.IsIAMMode() {
+		_, err := auth.iamCredentials.Get()
+		return err == nil
+	}
 	if auth.authResult != nil && auth.authResult.IdToken != nil {
 		return true
 	}
 	return false
 }
 func (auth *Auth) IsTokenExpired() bool {
+	if auth.IsIAMMode() {
+		return auth.iamCredentials.IsExpired()
+	}
 	if auth.authResult != nil && auth.authResult.ExpiresIn != nil {
 		expirationTime := auth.timeWhenLastJwtTokenWasRecieved.Add(time.Second * time.Duration(*auth.authResult.ExpiresIn))
 		if time.Now().Unix() < expirationTime.Unix() {
@@ -284,6 +374,43 @@ func (auth *Auth) IsTokenExpired() bool {
 	return true
 }
 
+func (auth *Auth) UserName() string {
+	return auth.userName
+}
+
+// TokenExpiresAt returns when the current jwt token expires, zero time if not logged in
+func (auth *Auth) TokenExpiresAt() time.Time {
+	if auth.authResult == nil || auth.authResult.ExpiresIn == nil {
+		return time.Time{}
+	}
+	return auth.timeWhenLastJwtTokenWasRecieved.Add(time.Second * time.Duration(*auth.authResult.ExpiresIn))
+}
+
+// AwsCredentialsExpiresAt returns when the AWS credentials used for signing expire, zero time if unknown
+func (auth *Auth) AwsCredentialsExpiresAt() time.Time {
+	if auth.IsIAMMode() {
+		expiresAt, err := auth.iamCredentials.ExpiresAt()
+		if err != nil {
+			return time.Time{}
+		}
+		return expiresAt
+	}
+	return auth.awsCredentialsExpiration
+}
+
+// IdTokenClaims decodes the claims of the id token. The token is not verified, it was received from Cognito directly
+func (auth *Auth) IdTokenClaims() (map[string]interface{}, error) {
+	if auth.authResult == nil || auth.authResult.IdToken == nil {
+		return nil, errors.New("not logged in")
+	}
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(*auth.authResult.IdToken, claims)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
 func promptMFA(promptMsg string) (string, error) {
 	// Implement logic for prompting MFA from the user
 	return "", errors.New("Prompting for MFA not implemented")
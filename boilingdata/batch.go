@@ -0,0 +1,77 @@
+package boilingdata
+
+import (
+	"context"
+	"sync"
+)
+
+// QueryResult is the outcome of one query of QueryAll
+type QueryResult struct {
+	Result *Result
+	Err    error
+}
+
+type batchConfig struct {
+	failFast bool
+}
+
+type BatchOption func(*batchConfig)
+
+// FailFast cancels the queries still running or waiting once a query failed
+func FailFast() BatchOption {
+	return func(c *batchConfig) {
+		c.failFast = true
+	}
+}
+
+// QueryAll sends the queries over the websocket of the instance with at most concurrency of them in flight
+// and returns their results in the order of the queries. The error is the first error in query order, with
+// FailFast the error that cancelled the other queries. The error of every query is in its QueryResult.
+func (instance *Instance) QueryAll(ctx context.Context, queries []Query, concurrency int, opts ...BatchOption) ([]QueryResult, error) {
+	cfg := batchConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if concurrency <= 0 {
+		concurrency = len(queries)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]QueryResult, len(queries))
+	slots := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	var failOnce sync.Once
+	var failFastErr error
+	for i, q := range queries {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, q Query) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result, err := instance.Execute(ctx, q)
+			results[i] = QueryResult{Result: result, Err: err}
+			if err != nil && cfg.failFast {
+				failOnce.Do(func() {
+					failFastErr = err
+					cancel()
+				})
+			}
+		}(i, q)
+	}
+	wg.Wait()
+	if failFastErr != nil {
+		return results, failFastErr
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			return results, r.Err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,293 @@
+package boilingdata
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bind replaces the ? or $1, $2.. placeholders of the SQL with the arguments formatted as SQL literals.
+// Placeholders inside string literals, quoted identifiers and comments are left alone. Both styles
+// can not be mixed and every argument must be used.
+//
+// Supported arguments are nil, strings, []byte, booleans, integers, floats, json.Number, big numbers,
+// time.Time, driver.Valuer, pointers to these and slices or arrays of these which become lists.
+func Bind(sql string, args ...interface{}) (string, error) {
+	var out strings.Builder
+	positional := 0
+	used := make([]bool, len(args))
+	style := byte(0)
+	setStyle := func(s byte) error {
+		if style != 0 && style != s {
+			return fmt.Errorf("boilingdata: can not mix ? and $n placeholders")
+		}
+		style = s
+		return nil
+	}
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		if end, _, err := literalEnd(sql, i); err != nil {
+			return "", err
+		} else if end > i {
+			out.WriteString(sql[i:end])
+			i = end
+			continue
+		}
+		switch {
+		case c == '?':
+			if err := setStyle('?'); err != nil {
+				return "", err
+			}
+			if positional >= len(args) {
+				return "", fmt.Errorf("boilingdata: not enough arguments for placeholder %d", positional+1)
+			}
+			if err := writeLiteral(&out, args[positional]); err != nil {
+				return "", fmt.Errorf("boilingdata: argument %d: %w", positional+1, err)
+			}
+			used[positional] = true
+			positional++
+			i++
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			if err := setStyle('$'); err != nil {
+				return "", err
+			}
+			j := i + 1
+			for j < len(sql) && isDigit(sql[j]) {
+				j++
+			}
+			n, _ := strconv.Atoi(sql[i+1 : j])
+			if n < 1 || n > len(args) {
+				return "", fmt.Errorf("boilingdata: no argument for placeholder $%d", n)
+			}
+			if err := writeLiteral(&out, args[n-1]); err != nil {
+				return "", fmt.Errorf("boilingdata: argument $%d: %w", n, err)
+			}
+			used[n-1] = true
+			i = j
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	for n, ok := range used {
+		if !ok {
+			return "", fmt.Errorf("boilingdata: argument %d is not used by any placeholder", n+1)
+		}
+	}
+	return out.String(), nil
+}
+
+// literalEnd returns the offset after the string literal, escape string, quoted identifier, dollar quoted
+// string or comment starting at offset i of the SQL, i if there is none there. comment is set for comments.
+func literalEnd(sql string, i int) (end int, comment bool, err error) {
+	c := sql[i]
+	switch {
+	case (c == 'E' || c == 'e') && i+1 < len(sql) && sql[i+1] == '\'' && (i == 0 || !isIdentChar(sql[i-1])):
+		// Escape string, E'it\'s', backslashes escape the next character
+		for j := i + 2; j < len(sql); j++ {
+			switch {
+			case sql[j] == '\\':
+				j++
+			case sql[j] == '\'' && j+1 < len(sql) && sql[j+1] == '\'':
+				j++
+			case sql[j] == '\'':
+				return j + 1, false, nil
+			}
+		}
+		return 0, false, fmt.Errorf("boilingdata: unterminated quoted text at offset %d", i)
+	case c == '\'' || c == '"':
+		end := quotedEnd(sql, i, c)
+		if end < 0 {
+			return 0, false, fmt.Errorf("boilingdata: unterminated quoted text at offset %d", i)
+		}
+		return end, false, nil
+	case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+		end := strings.IndexByte(sql[i:], '\n')
+		if end < 0 {
+			return len(sql), true, nil
+		}
+		return i + end, true, nil
+	case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+		end := strings.Index(sql[i+2:], "*/")
+		if end < 0 {
+			return 0, true, fmt.Errorf("boilingdata: unterminated comment at offset %d", i)
+		}
+		return i + 2 + end + 2, true, nil
+	case c == '$' && i+1 < len(sql) && !isDigit(sql[i+1]):
+		// Dollar quoted string, $$text$$ or $tag$text$tag$
+		tagEnd := i + 1
+		for tagEnd < len(sql) && isIdentChar(sql[tagEnd]) {
+			tagEnd++
+		}
+		if tagEnd >= len(sql) || sql[tagEnd] != '$' {
+			return i, false, nil
+		}
+		tag := sql[i : tagEnd+1]
+		end := strings.Index(sql[tagEnd+1:], tag)
+		if end < 0 {
+			return 0, false, fmt.Errorf("boilingdata: unterminated dollar quoted text at offset %d", i)
+		}
+		return tagEnd + 1 + end + len(tag), false, nil
+	}
+	return i, false, nil
+}
+
+// quotedEnd returns the offset after the closing quote, quotes are escaped by doubling them
+func quotedEnd(sql string, start int, quote byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		if sql[i] != quote {
+			continue
+		}
+		if i+1 < len(sql) && sql[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return -1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func writeLiteral(out *strings.Builder, arg interface{}) error {
+	if rv := reflect.ValueOf(arg); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		out.WriteString("NULL")
+		return nil
+	}
+	if valuer, ok := arg.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		arg = v
+	}
+	switch v := arg.(type) {
+	case nil:
+		out.WriteString("NULL")
+	case string:
+		out.WriteString(quoteString(v))
+	case []byte:
+		out.WriteString("'")
+		for _, b := range v {
+			out.WriteString(`\x`)
+			out.WriteString(strings.ToUpper(hex.EncodeToString([]byte{b})))
+		}
+		out.WriteString("'::BLOB")
+	case bool:
+		if v {
+			out.WriteString("TRUE")
+		} else {
+			out.WriteString("FALSE")
+		}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		writeNumber(out, fmt.Sprintf("%d", v))
+	case float32:
+		writeFloat(out, float64(v), 32)
+	case float64:
+		writeFloat(out, v, 64)
+	case json.Number:
+		if !decimalLiteral.MatchString(v.String()) {
+			return fmt.Errorf("invalid number %q", v.String())
+		}
+		writeNumber(out, v.String())
+	case *big.Int:
+		writeNumber(out, v.String())
+	case *big.Float:
+		writeNumber(out, v.Text('f', -1))
+	case *big.Rat:
+		writeNumber(out, v.FloatString(bigRatDigits(v)))
+	case time.Time:
+		out.WriteString("TIMESTAMP '")
+		out.WriteString(v.UTC().Format("2006-01-02 15:04:05.999999"))
+		out.WriteString("'")
+	default:
+		rv := reflect.ValueOf(arg)
+		switch rv.Kind() {
+		case reflect.Ptr:
+			return writeLiteral(out, rv.Elem().Interface())
+		case reflect.Slice, reflect.Array:
+			out.WriteString("[")
+			for i := 0; i < rv.Len(); i++ {
+				if i > 0 {
+					out.WriteString(", ")
+				}
+				if err := writeLiteral(out, rv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			out.WriteString("]")
+		case reflect.String:
+			out.WriteString(quoteString(rv.String()))
+		case reflect.Bool:
+			return writeLiteral(out, rv.Bool())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return writeLiteral(out, rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return writeLiteral(out, rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			return writeLiteral(out, rv.Float())
+		default:
+			return fmt.Errorf("unsupported type %T", arg)
+		}
+	}
+	return nil
+}
+
+// decimalLiteral is the grammar of JSON numbers, e.g. Inf, NaN and hex floats are not numbers in SQL
+var decimalLiteral = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func writeFloat(out *strings.Builder, f float64, bits int) {
+	switch {
+	case math.IsNaN(f):
+		out.WriteString("'NaN'::DOUBLE")
+	case math.IsInf(f, 1):
+		out.WriteString("'Infinity'::DOUBLE")
+	case math.IsInf(f, -1):
+		out.WriteString("'-Infinity'::DOUBLE")
+	default:
+		writeNumber(out, strconv.FormatFloat(f, 'g', -1, bits))
+	}
+}
+
+// writeNumber puts negative numbers in parentheses, so 5-? with -1 isn't written as the comment 5--1
+func writeNumber(out *strings.Builder, number string) {
+	if strings.HasPrefix(number, "-") {
+		out.WriteString("(" + number + ")")
+		return
+	}
+	out.WriteString(number)
+}
+
+// bigRatDigits returns enough decimal digits to write the rational exactly if it is a finite decimal
+func bigRatDigits(r *big.Rat) int {
+	digits := 0
+	d := new(big.Int).Set(r.Denom())
+	ten := big.NewInt(10)
+	for d.Cmp(big.NewInt(1)) != 0 && digits < 100 {
+		g := new(big.Int).GCD(nil, nil, d, ten)
+		if g.Cmp(big.NewInt(1)) == 0 {
+			return 100
+		}
+		d.Quo(d, g)
+		digits++
+	}
+	return digits
+}
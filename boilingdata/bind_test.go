@@ -0,0 +1,77 @@
+package boilingdata
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		sql  string
+		args []interface{}
+		want string
+	}{
+		{"SELECT ?", []interface{}{"it's"}, "SELECT 'it''s'"},
+		{"SELECT ?, ?", []interface{}{1, nil}, "SELECT 1, NULL"},
+		{"SELECT $2, $1, $2", []interface{}{"a", true}, "SELECT TRUE, 'a', TRUE"},
+		{"SELECT '?', ?", []interface{}{1}, "SELECT '?', 1"},
+		{"SELECT 'it''s ?', ?", []interface{}{1}, "SELECT 'it''s ?', 1"},
+		{`SELECT "col?" FROM t WHERE a = ?`, []interface{}{1}, `SELECT "col?" FROM t WHERE a = 1`},
+		{`SELECT "a""?" , ?`, []interface{}{1}, `SELECT "a""?" , 1`},
+		{"SELECT ? -- why?\n, ?", []interface{}{1, 2}, "SELECT 1 -- why?\n, 2"},
+		{"SELECT /* $1 ? */ $1", []interface{}{1}, "SELECT /* $1 ? */ 1"},
+		{"SELECT $$it's ?$$, ?", []interface{}{1}, "SELECT $$it's ?$$, 1"},
+		{"SELECT $q$ $1 $q$, $1", []interface{}{1}, "SELECT $q$ $1 $q$, 1"},
+		{`SELECT E'it\'s ?', ?`, []interface{}{1}, `SELECT E'it\'s ?', 1`},
+		{`SELECT e'a\\', ?`, []interface{}{1}, `SELECT e'a\\', 1`},
+		{"SELECT name FROM t WHERE x = ?", []interface{}{json.Number("-1.5e10")}, "SELECT name FROM t WHERE x = (-1.5e10)"},
+		{"SELECT ?", []interface{}{[]int{1, 2}}, "SELECT [1, 2]"},
+		{"SELECT * FROM t WHERE a = 5-? AND owner = 'me'", []interface{}{-1}, "SELECT * FROM t WHERE a = 5-(-1) AND owner = 'me'"},
+		{"SELECT 5-$1", []interface{}{-1.5}, "SELECT 5-(-1.5)"},
+		{"SELECT -?, -?, -?, -?, -?, -?", []interface{}{int8(-1), -2.5, json.Number("-3"), big.NewInt(-4), big.NewFloat(-5.5), big.NewRat(-1, 4)},
+			"SELECT -(-1), -(-2.5), -(-3), -(-4), -(-5.5), -(-0.25)"},
+		{"SELECT ?", []interface{}{[]int{-1, 2}}, "SELECT [(-1), 2]"},
+	}
+	for _, test := range tests {
+		got, err := Bind(test.sql, test.args...)
+		if err != nil {
+			t.Errorf("Bind(%q) failed: %v", test.sql, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Bind(%q) = %q, want %q", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		args []interface{}
+	}{
+		{"mixed placeholder styles", "SELECT ?, $1", []interface{}{1}},
+		{"mixed placeholder styles", "SELECT $1, ?", []interface{}{1}},
+		{"unused argument", "SELECT ?", []interface{}{1, 2}},
+		{"unused numbered argument", "SELECT $2", []interface{}{1, 2}},
+		{"placeholder in text only", "SELECT '?'", []interface{}{1}},
+		{"not enough arguments", "SELECT ?, ?", []interface{}{1}},
+		{"no argument", "SELECT $3", []interface{}{1}},
+		{"unterminated quote", "SELECT 'abc, ?", []interface{}{1}},
+		{"unterminated escape string", `SELECT E'abc\', ?`, []interface{}{1}},
+		{"unterminated comment", "SELECT ? /* abc", []interface{}{1}},
+		{"unterminated dollar quote", "SELECT $$abc, ?", []interface{}{1}},
+		{"Inf", "SELECT ?", []interface{}{json.Number("Inf")}},
+		{"NaN", "SELECT ?", []interface{}{json.Number("NaN")}},
+		{"infinity", "SELECT ?", []interface{}{json.Number("infinity")}},
+		{"hex float", "SELECT ?", []interface{}{json.Number("0x1p4")}},
+		{"number with SQL", "SELECT ?", []interface{}{json.Number("1; DROP TABLE t")}},
+		{"unsupported type", "SELECT ?", []interface{}{struct{}{}}},
+	}
+	for _, test := range tests {
+		if got, err := Bind(test.sql, test.args...); err == nil {
+			t.Errorf("%s: Bind(%q) = %q, want an error", test.name, test.sql, got)
+		}
+	}
+}
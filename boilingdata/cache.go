@@ -0,0 +1,401 @@
+package boilingdata
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// CacheControl decides how a query uses the client side result cache
+type CacheControl int
+
+const (
+	// CacheUse answers from the cache when possible and stores new results
+	CacheUse CacheControl = iota
+	// CacheBypass neither reads nor writes the cache
+	CacheBypass
+	// CacheRefresh runs the query and replaces the cached result
+	CacheRefresh
+)
+
+func WithCacheControl(control CacheControl) QueryOption {
+	return func(q *Query) {
+		q.CacheControl = control
+	}
+}
+
+// ResultCache stores results by a key made of the normalized SQL, the tags and the read cache mode.
+// Cached results are shared, they must not be modified.
+type ResultCache interface {
+	Get(key string) (*Result, bool)
+	Set(key string, result *Result)
+	Stats() ResultCacheStats
+}
+
+type ResultCacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+	Entries int     `json:"entries"`
+	Bytes   int64   `json:"bytes"`
+}
+
+// ResultCacheConfig enables the result cache of instances, Type is "memory" or "disk"
+type ResultCacheConfig struct {
+	Type       string `json:"type"`
+	TTLSeconds int    `json:"ttlSeconds"`
+	MaxEntries int    `json:"maxEntries"`
+	MaxBytes   int64  `json:"maxBytes"`
+	Dir        string `json:"dir"`
+}
+
+func newResultCache(cfg ResultCacheConfig, userName string) ResultCache {
+	ttl := time.Duration(cfg.TTLSeconds) * time.Second
+	switch cfg.Type {
+	case "memory":
+		return NewMemoryCache(ttl, cfg.MaxEntries, cfg.MaxBytes)
+	case "disk":
+		// Every user gets its own directory, results are never shared between users
+		sum := sha256.Sum256([]byte(userName))
+		cache, err := NewDiskCache(filepath.Join(cfg.Dir, hex.EncodeToString(sum[:8])), ttl, cfg.MaxBytes)
+		if err != nil {
+			log.Println("Could not create disk cache, caching disabled: " + err.Error())
+			return nil
+		}
+		return cache
+	}
+	return nil
+}
+
+// CacheKey returns the cache key of a payload. Whitespace outside of literals and trailing semicolons
+// of the SQL are ignored, as is the order of the tags.
+func CacheKey(payload models.Payload) string {
+	tags := make([]string, len(payload.Tags))
+	for i, tag := range payload.Tags {
+		tags[i] = tag.Name + "=" + tag.Value
+	}
+	sort.Strings(tags)
+	h := sha256.New()
+	h.Write([]byte(NormalizeSQL(payload.SQL)))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(tags, "\x00")))
+	h.Write([]byte{0})
+	h.Write([]byte(payload.ReadCache))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NormalizeSQL collapses whitespace and comments outside of quoted text to single spaces and trims
+// trailing semicolons
+func NormalizeSQL(sql string) string {
+	var out strings.Builder
+	space := false
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		end, comment, err := literalEnd(sql, i)
+		if err != nil {
+			// Unterminated, the rest of the SQL is kept as it is
+			end = len(sql)
+		}
+		if comment {
+			space = out.Len() > 0
+			i = end - 1
+			continue
+		}
+		if end > i {
+			if space {
+				out.WriteByte(' ')
+				space = false
+			}
+			out.WriteString(sql[i:end])
+			i = end - 1
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			space = out.Len() > 0
+			continue
+		}
+		if space {
+			out.WriteByte(' ')
+			space = false
+		}
+		out.WriteByte(c)
+	}
+	return strings.TrimRight(out.String(), "; ")
+}
+
+// cacheCounters counts hits and misses of a cache
+type cacheCounters struct {
+	hits   int64
+	misses int64
+}
+
+func (c *cacheCounters) count(hit bool) {
+	if hit {
+		atomic.AddInt64(&c.hits, 1)
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+}
+
+func (c *cacheCounters) stats(entries int, bytes int64) ResultCacheStats {
+	stats := ResultCacheStats{
+		Hits:    atomic.LoadInt64(&c.hits),
+		Misses:  atomic.LoadInt64(&c.misses),
+		Entries: entries,
+		Bytes:   bytes,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// MemoryCache keeps results in memory, evicting the least recently used once maxEntries or maxBytes
+// is exceeded. Zero limits mean no limit.
+type MemoryCache struct {
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int64
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+	bytes      int64
+	counters   cacheCounters
+}
+
+type memoryEntry struct {
+	key     string
+	result  *Result
+	size    int64
+	expires time.Time
+}
+
+func NewMemoryCache(ttl time.Duration, maxEntries int, maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (c *MemoryCache) Get(key string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if ok && c.ttl > 0 && time.Now().After(el.Value.(*memoryEntry).expires) {
+		c.remove(el)
+		ok = false
+	}
+	c.counters.count(ok)
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*memoryEntry).result, true
+}
+
+func (c *MemoryCache) Set(key string, result *Result) {
+	size := resultSize(result)
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	entry := &memoryEntry{key: key, result: result, size: size, expires: time.Now().Add(c.ttl)}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.bytes += size
+	for (c.maxEntries > 0 && c.lru.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *MemoryCache) Stats() ResultCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counters.stats(c.lru.Len(), c.bytes)
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	entry := el.Value.(*memoryEntry)
+	c.lru.Remove(el)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+}
+
+// resultSize estimates the size of the rows of a result as JSON without encoding them
+func resultSize(result *Result) int64 {
+	size := int64(2)
+	for _, row := range result.Rows {
+		size += valueSize(row) + 1
+	}
+	return size
+}
+
+func valueSize(v interface{}) int64 {
+	switch v := v.(type) {
+	case nil:
+		return 4
+	case string:
+		return int64(len(v)) + 2
+	case json.Number:
+		return int64(len(v))
+	case map[string]interface{}:
+		size := int64(2)
+		for key, value := range v {
+			size += int64(len(key)) + 4 + valueSize(value)
+		}
+		return size
+	case []interface{}:
+		size := int64(2)
+		for _, value := range v {
+			size += valueSize(value) + 1
+		}
+		return size
+	default:
+		return 8
+	}
+}
+
+// DiskCache keeps results as JSON files in a directory, removing the oldest files once maxBytes is exceeded
+type DiskCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	mu       sync.Mutex
+	counters cacheCounters
+}
+
+type diskEntry struct {
+	Expires time.Time `json:"expires"`
+	Result  *Result   `json:"result"`
+	// Response is the websocket response of the result without its rows, returned by Instance.Query
+	Response *models.Response `json:"response"`
+}
+
+func NewDiskCache(dir string, ttl time.Duration, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir, ttl: ttl, maxBytes: maxBytes}, nil
+}
+
+func (c *DiskCache) Get(key string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.read(key)
+	c.counters.count(ok)
+	return result, ok
+}
+
+func (c *DiskCache) read(key string) (*Result, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry diskEntry
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&entry); err != nil || entry.Result == nil || entry.Response == nil {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	if c.ttl > 0 && time.Now().After(entry.Expires) {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	response := entry.Response
+	response.Data = entry.Result.Rows
+	response.Schema = entry.Result.Schema
+	response.CacheInfos = entry.Result.Meta.Cache.Info
+	response.Bytes = entry.Result.Meta.Bytes
+	entry.Result.response = response
+	return entry.Result, true
+}
+
+func (c *DiskCache) Set(key string, result *Result) {
+	if result.response == nil {
+		return
+	}
+	response := *result.response
+	response.Data = nil
+	response.Schema = nil
+	data, err := json.Marshal(diskEntry{Expires: time.Now().Add(c.ttl), Result: result, Response: &response})
+	if err != nil || (c.maxBytes > 0 && int64(len(data)) > c.maxBytes) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.WriteFile(c.path(key), data, 0600); err != nil {
+		log.Println("Could not write result to disk cache: " + err.Error())
+		return
+	}
+	c.evict()
+}
+
+func (c *DiskCache) Stats() ResultCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files, total := c.files()
+	return c.counters.stats(len(files), total)
+}
+
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *DiskCache) files() ([]os.FileInfo, int64) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, 0
+	}
+	var files []os.FileInfo
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+		total += info.Size()
+	}
+	return files, total
+}
+
+// evict removes the oldest files until the cache fits in maxBytes
+func (c *DiskCache) evict() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	files, total := c.files()
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, f := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if os.Remove(filepath.Join(c.dir, f.Name())) == nil {
+			total -= f.Size()
+		}
+	}
+}
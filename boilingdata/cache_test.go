@@ -0,0 +1,66 @@
+package boilingdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT  a,\n\tb FROM t;", "SELECT a, b FROM t"},
+		{"  SELECT 1 ;; ", "SELECT 1"},
+		{"SELECT 'a  b' FROM t", "SELECT 'a  b' FROM t"},
+		{"SELECT 'it''s  here' ,  \"my  col\"", "SELECT 'it''s  here' , \"my  col\""},
+		{"SELECT a -- x\nFROM t", "SELECT a FROM t"},
+		{"SELECT a -- x FROM t", "SELECT a"},
+		{"SELECT a /* x\n y */ FROM t", "SELECT a FROM t"},
+		{"SELECT '-- not a comment'  FROM t", "SELECT '-- not a comment' FROM t"},
+		{"SELECT $$a  -- b$$  FROM t", "SELECT $$a  -- b$$ FROM t"},
+		{"SELECT $tag$a  'b$tag$", "SELECT $tag$a  'b$tag$"},
+		{"SELECT 'unterminated  text", "SELECT 'unterminated  text"},
+	}
+	for _, test := range tests {
+		if got := NormalizeSQL(test.sql); got != test.want {
+			t.Errorf("NormalizeSQL(%q) = %q, want %q", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestCacheKeyComments(t *testing.T) {
+	commented := CacheKey(payloadOf("SELECT a -- x\nFROM t"))
+	if commented == CacheKey(payloadOf("SELECT a -- x FROM t")) {
+		t.Error("a comment ending at a newline has the cache key of a comment running to the end")
+	}
+	if commented != CacheKey(payloadOf("SELECT a\nFROM t")) {
+		t.Error("comments change the cache key")
+	}
+}
+
+func payloadOf(sql string) models.Payload {
+	return models.Payload{SQL: sql, ReadCache: models.CacheNone}
+}
+
+func TestDiskCacheKeepsResponse(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir(), time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := &models.Response{MessageType: "DATA", RequestID: "r1", TotalBatches: 1, Data: []map[string]interface{}{{"a": "x"}}}
+	cache.Set("k", newResult(response))
+	hit, ok := cache.Get("k")
+	if !ok {
+		t.Fatal("no cache hit")
+	}
+	got := hit.response
+	if got == nil {
+		t.Fatal("cached result has no response")
+	}
+	if got.MessageType != "DATA" || got.RequestID != "r1" || len(got.Data) != 1 || got.Data[0]["a"] != "x" {
+		t.Errorf("cached response = %+v", got)
+	}
+}
@@ -0,0 +1,161 @@
+package boilingdata
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// Client is the entry point for using BoilingData from Go code
+//
+//	client, err := boilingdata.NewClient(boilingdata.WithTimeout(time.Minute))
+//	result, err := client.Query(ctx, "SELECT * FROM parquet_scan('s3://boilingdata-demo/demo.parquet') LIMIT 10")
+type Client struct {
+	config         Config
+	credentials    CredentialProvider
+	iamCredentials *credentials.Credentials
+	timeout        time.Duration
+	defaultTags    []models.Tag
+	logger         *log.Logger
+	cache          ResultCache
+	retry          *RetryPolicy
+	instance       *Instance
+}
+
+type Option func(*Client)
+
+func WithConfig(cfg Config) Option {
+	return func(c *Client) {
+		c.config = cfg
+	}
+}
+
+// WithCredentials sets where the username and password come from, DefaultCredentialChain if not set
+func WithCredentials(provider CredentialProvider) Option {
+	return func(c *Client) {
+		c.credentials = provider
+	}
+}
+
+// WithIAMCredentials signs the websocket request with AWS credentials instead of logging in through Cognito
+func WithIAMCredentials(iamCredentials *credentials.Credentials) Option {
+	return func(c *Client) {
+		c.iamCredentials = iamCredentials
+	}
+}
+
+// WithTimeout limits queries whose context has no deadline
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithDefaultTags sets tags sent with every query, on top of the default tags of the config
+func WithDefaultTags(tags ...models.Tag) Option {
+	return func(c *Client) {
+		c.defaultTags = tags
+	}
+}
+
+// WithCache enables the client side result cache, e.g. NewMemoryCache or NewDiskCache
+func WithCache(cache ResultCache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// WithRetryPolicy sets how failed queries are retried, see RetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = &policy
+	}
+}
+
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+func NewClient(opts ...Option) (*Client, error) {
+	c := &Client{config: DefaultConfig(), logger: log.Default()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	var auth *Auth
+	if c.iamCredentials != nil {
+		auth = NewIAMAuth("iam", c.iamCredentials)
+	} else {
+		if c.credentials == nil {
+			c.credentials = DefaultCredentialChain()
+		}
+		creds, err := c.credentials.Retrieve()
+		if err != nil {
+			return nil, err
+		}
+		auth = &Auth{userName: creds.UserName, password: creds.Password}
+	}
+	c.config.DefaultTags = models.MergeTags(c.config.DefaultTags, c.defaultTags)
+	if c.retry != nil {
+		c.config.Retry = *c.retry
+	}
+	auth.config = &c.config
+	c.instance = newInstance(auth)
+	if c.cache != nil {
+		c.instance.Cache = c.cache
+	}
+	return c, nil
+}
+
+// Query runs the SQL and waits for the complete result. Arguments are bound to the ? or $1 placeholders
+// of the SQL, QueryOption values among them are applied to the query instead
+//
+//	client.Query(ctx, "SELECT * FROM t WHERE id = ? AND name = ?", 42, "it's", boilingdata.WithReadCache(models.CacheRefresh))
+func (c *Client) Query(ctx context.Context, sql string, args ...interface{}) (*Result, error) {
+	q := Query{SQL: sql}
+	for _, arg := range args {
+		if opt, ok := arg.(QueryOption); ok {
+			opt(&q)
+		} else {
+			q.Args = append(q.Args, arg)
+		}
+	}
+	if q.RequestID == "" {
+		q.RequestID = newRequestID()
+	}
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	result, err := c.instance.Execute(ctx, q)
+	if err != nil {
+		c.logger.Printf("Query %s failed: %v", q.RequestID, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryAll runs the queries with at most concurrency of them in flight, see Instance.QueryAll
+func (c *Client) QueryAll(ctx context.Context, queries []Query, concurrency int, opts ...BatchOption) ([]QueryResult, error) {
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	return c.instance.QueryAll(ctx, queries, concurrency, opts...)
+}
+
+// Instance returns the instance the client sends its queries through
+func (c *Client) Instance() *Instance {
+	return c.instance
+}
+
+// Close closes the websocket of the client
+func (c *Client) Close() {
+	c.instance.Wsc.Close()
+}
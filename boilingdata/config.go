@@ -0,0 +1,81 @@
+package boilingdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pavi6691/go-boilingdata/constants"
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// Config of the BoilingData endpoints, defaults to the values in constants
+type Config struct {
+	Region             string `json:"region"`
+	UserPoolID         string `json:"userPoolId"`
+	ClientID           string `json:"clientId"`
+	IdentityPoolID     string `json:"identityPoolId"`
+	WssURL             string `json:"wssUrl"`
+	IdleTimeoutMinutes int    `json:"idleTimeoutMinutes"`
+	// DefaultTags are sent with every query, UserTags per username override them and
+	// the tags of a query override both
+	DefaultTags []models.Tag            `json:"defaultTags"`
+	UserTags    map[string][]models.Tag `json:"userTags"`
+	TagPolicy   models.TagPolicy        `json:"tagPolicy"`
+	ResultCache ResultCacheConfig       `json:"resultCache"`
+	Retry       RetryPolicy             `json:"retry"`
+	// JobRetentionMinutes is how long finished jobs and their results are kept, 60 by default
+	JobRetentionMinutes int `json:"jobRetentionMinutes"`
+	// Cursors keep results of /query paged with pageSize
+	Cursors CursorConfig `json:"cursors"`
+}
+
+var configMu sync.RWMutex
+var defaultConfig = Config{
+	Region:         constants.Region,
+	UserPoolID:     constants.PoolID,
+	ClientID:       constants.ClientID,
+	IdentityPoolID: constants.IdentityPoolId,
+	WssURL:         constants.WssUrl,
+}
+
+// DefaultConfig returns the config used by instances created without one
+func DefaultConfig() Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return defaultConfig
+}
+
+// SetDefaultConfig sets the config of instances created without one, e.g. from a config file of the server
+func SetDefaultConfig(cfg Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	defaultConfig = cfg
+}
+
+// tagsFor merges the default tags, the tags of the user and the tags of the query and validates them
+func (cfg Config) tagsFor(userName string, tags []models.Tag) ([]models.Tag, error) {
+	merged := models.MergeTags(cfg.DefaultTags, cfg.UserTags[userName], tags)
+	if err := cfg.TagPolicy.Validate(merged); err != nil {
+		return nil, fmt.Errorf("invalid tags: %v", err)
+	}
+	return merged, nil
+}
+
+// LoadConfig reads a JSON config file, fields not set in the file keep their default values
+func LoadConfig(filename string) (Config, error) {
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config file %s: %v", filename, err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config file %s: %v", filename, err)
+	}
+	return cfg, nil
+}
+
+func (cfg Config) cognitoIdp() string {
+	return fmt.Sprintf("cognito-idp.%s.amazonaws.com/%s", cfg.Region, cfg.UserPoolID)
+}
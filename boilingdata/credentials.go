@@ -0,0 +1,301 @@
+package boilingdata
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	EnvUserName          = "BD_USERNAME"
+	EnvPassword          = "BD_PASSWORD"
+	EnvProfile           = "BD_PROFILE"
+	EnvCredentialsFile   = "BD_CREDENTIALS_FILE"
+	EnvSecretsFile       = "BD_SECRETS_FILE"
+	EnvSecretsPassphrase = "BD_SECRETS_PASSPHRASE"
+	DefaultProfile       = "default"
+	secretsMagic         = "BDENC2"
+	secretsSaltSize      = 16
+	// scrypt cost parameters of new secrets files, stored in the file as log2(N), r and p
+	secretsLogN = 15
+	secretsR    = 8
+	secretsP    = 1
+	// upper bounds of the stored parameters so a crafted file can't make decryption run for hours
+	secretsMaxLogN = 20
+	secretsMaxRP   = 16
+)
+
+// ErrNoCredentials is returned by a provider that has nothing to offer, so a chain can move on to the next one.
+var ErrNoCredentials = errors.New("no boilingdata credentials found")
+
+// Credentials of a BoilingData account
+type Credentials struct {
+	UserName string
+	Password string
+	Source   string
+}
+
+// CredentialProvider retrieves credentials from a single source.
+type CredentialProvider interface {
+	Retrieve() (Credentials, error)
+}
+
+// StaticProvider returns the credentials it was created with.
+type StaticProvider struct {
+	UserName string
+	Password string
+}
+
+func NewStaticProvider(userName string, password string) *StaticProvider {
+	return &StaticProvider{UserName: userName, Password: password}
+}
+
+func (p *StaticProvider) Retrieve() (Credentials, error) {
+	if p.UserName == "" || p.Password == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	return Credentials{UserName: p.UserName, Password: p.Password, Source: "static"}, nil
+}
+
+// EnvProvider reads credentials from BD_USERNAME and BD_PASSWORD.
+type EnvProvider struct{}
+
+func (p *EnvProvider) Retrieve() (Credentials, error) {
+	userName := os.Getenv(EnvUserName)
+	password := os.Getenv(EnvPassword)
+	if userName == "" || password == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	return Credentials{UserName: userName, Password: password, Source: "env"}, nil
+}
+
+// FileProvider reads a profile from an ini style credentials file, ~/.boilingdata/credentials by default.
+//
+//	[default]
+//	username = me@example.com
+//	password = secret
+type FileProvider struct {
+	Filename string
+	Profile  string
+}
+
+func (p *FileProvider) Retrieve() (Credentials, error) {
+	filename := p.Filename
+	if filename == "" {
+		filename = os.Getenv(EnvCredentialsFile)
+	}
+	if filename == "" {
+		filename = defaultConfigPath("credentials")
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Credentials{}, ErrNoCredentials
+		}
+		return Credentials{}, fmt.Errorf("failed to read credentials file %s: %v", filename, err)
+	}
+	return credentialsFromProfiles(data, profileName(p.Profile), "file:"+filename)
+}
+
+// EncryptedFileProvider reads a profile from a credentials file encrypted with EncryptSecrets,
+// ~/.boilingdata/secrets.enc by default. The passphrase is taken from BD_SECRETS_PASSPHRASE when not set.
+type EncryptedFileProvider struct {
+	Filename   string
+	Profile    string
+	Passphrase string
+}
+
+func (p *EncryptedFileProvider) Retrieve() (Credentials, error) {
+	filename := p.Filename
+	if filename == "" {
+		filename = os.Getenv(EnvSecretsFile)
+	}
+	if filename == "" {
+		filename = defaultConfigPath("secrets.enc")
+	}
+	passphrase := p.Passphrase
+	if passphrase == "" {
+		passphrase = os.Getenv(EnvSecretsPassphrase)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Credentials{}, ErrNoCredentials
+		}
+		return Credentials{}, fmt.Errorf("failed to read secrets file %s: %v", filename, err)
+	}
+	if passphrase == "" {
+		return Credentials{}, fmt.Errorf("secrets file %s found but %s is not set", filename, EnvSecretsPassphrase)
+	}
+	plain, err := DecryptSecrets(data, passphrase)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to decrypt secrets file %s: %v", filename, err)
+	}
+	return credentialsFromProfiles(plain, profileName(p.Profile), "secrets:"+filename)
+}
+
+// ChainProvider tries each provider in order and returns the first credentials found.
+type ChainProvider struct {
+	Providers []CredentialProvider
+}
+
+func NewChainProvider(providers ...CredentialProvider) *ChainProvider {
+	return &ChainProvider{Providers: providers}
+}
+
+// DefaultCredentialChain looks for credentials in the environment, the credentials file
+// and the encrypted secrets file, in that order.
+func DefaultCredentialChain() *ChainProvider {
+	return NewChainProvider(&EnvProvider{}, &FileProvider{}, &EncryptedFileProvider{})
+}
+
+func (c *ChainProvider) Retrieve() (Credentials, error) {
+	for _, p := range c.Providers {
+		creds, err := p.Retrieve()
+		if err == nil {
+			return creds, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) {
+			return Credentials{}, err
+		}
+	}
+	return Credentials{}, ErrNoCredentials
+}
+
+// LoginWithProvider retrieves credentials from the provider and logs in that user.
+func LoginWithProvider(provider CredentialProvider) (*Instance, error) {
+	creds, err := provider.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+	return Login(creds.UserName, creds.Password, "")
+}
+
+// EncryptSecrets encrypts a credentials file with AES-256-GCM using a key derived from the passphrase with scrypt.
+// The file is the magic, the scrypt parameters, the salt, the nonce and the sealed data.
+func EncryptSecrets(plain []byte, passphrase string) ([]byte, error) {
+	header := append([]byte(secretsMagic), secretsLogN, secretsR, secretsP)
+	salt := make([]byte, secretsSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	gcm, err := secretsCipher(passphrase, salt, header[len(secretsMagic):])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	out := append(append([]byte{}, header...), salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plain, header), nil
+}
+
+// DecryptSecrets reverses EncryptSecrets.
+func DecryptSecrets(data []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(secretsMagic)) {
+		return nil, errors.New("not a boilingdata secrets file")
+	}
+	headerSize := len(secretsMagic) + 3
+	if len(data) < headerSize+secretsSaltSize {
+		return nil, errors.New("secrets file is truncated")
+	}
+	header, data := data[:headerSize], data[headerSize:]
+	salt, data := data[:secretsSaltSize], data[secretsSaltSize:]
+	gcm, err := secretsCipher(passphrase, salt, header[len(secretsMagic):])
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("secrets file is truncated")
+	}
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, data, header)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted secrets file")
+	}
+	return plain, nil
+}
+
+// secretsCipher derives the AES-256 key with scrypt, params are log2(N), r and p
+func secretsCipher(passphrase string, salt []byte, params []byte) (cipher.AEAD, error) {
+	logN, r, p := int(params[0]), int(params[1]), int(params[2])
+	if logN < 1 || logN > secretsMaxLogN || r < 1 || r > secretsMaxRP || p < 1 || p > secretsMaxRP {
+		return nil, fmt.Errorf("unsupported scrypt parameters N=2^%d r=%d p=%d", logN, r, p)
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<logN, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func credentialsFromProfiles(data []byte, profile string, source string) (Credentials, error) {
+	values, ok := parseProfiles(data)[profile]
+	if !ok {
+		return Credentials{}, ErrNoCredentials
+	}
+	creds := Credentials{UserName: values["username"], Password: values["password"], Source: source}
+	if creds.UserName == "" || creds.Password == "" {
+		return Credentials{}, fmt.Errorf("profile %s in %s must set username and password", profile, source)
+	}
+	return creds, nil
+}
+
+// parseProfiles parses an ini style file into profile -> key -> value
+func parseProfiles(data []byte) map[string]map[string]string {
+	profiles := make(map[string]map[string]string)
+	var current map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(strings.TrimPrefix(line[1:len(line)-1], "profile "))
+			current = make(map[string]string)
+			profiles[name] = current
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || current == nil {
+			continue
+		}
+		current[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return profiles
+}
+
+func profileName(profile string) string {
+	if profile != "" {
+		return profile
+	}
+	if env := os.Getenv(EnvProfile); env != "" {
+		return env
+	}
+	return DefaultProfile
+}
+
+func defaultConfigPath(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".boilingdata", name)
+	}
+	return filepath.Join(home, ".boilingdata", name)
+}
@@ -0,0 +1,253 @@
+package boilingdata
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecretsRoundTrip(t *testing.T) {
+	plain := []byte("[default]\nusername = me@example.com\npassword = secret\n")
+	encrypted, err := EncryptSecrets(plain, "passphrase")
+	if err != nil {
+		t.Fatalf("EncryptSecrets: %v", err)
+	}
+	if bytes.Contains(encrypted, []byte("secret")) {
+		t.Error("encrypted file contains the password")
+	}
+	decrypted, err := DecryptSecrets(encrypted, "passphrase")
+	if err != nil {
+		t.Fatalf("DecryptSecrets: %v", err)
+	}
+	if !bytes.Equal(decrypted, plain) {
+		t.Errorf("DecryptSecrets = %q, want %q", decrypted, plain)
+	}
+}
+
+func TestSecretsWrongPassphrase(t *testing.T) {
+	encrypted, err := EncryptSecrets([]byte("[default]\n"), "passphrase")
+	if err != nil {
+		t.Fatalf("EncryptSecrets: %v", err)
+	}
+	if _, err := DecryptSecrets(encrypted, "wrong"); err == nil {
+		t.Error("DecryptSecrets with a wrong passphrase succeeded")
+	}
+}
+
+func TestSecretsTamperedParameters(t *testing.T) {
+	encrypted, err := EncryptSecrets([]byte("[default]\n"), "passphrase")
+	if err != nil {
+		t.Fatalf("EncryptSecrets: %v", err)
+	}
+	tests := []struct {
+		name string
+		logN byte
+	}{
+		{"lower cost", secretsLogN - 1},
+		{"cost above the limit", secretsMaxLogN + 1},
+	}
+	for _, test := range tests {
+		tampered := append([]byte{}, encrypted...)
+		tampered[len(secretsMagic)] = test.logN
+		if _, err := DecryptSecrets(tampered, "passphrase"); err == nil {
+			t.Errorf("%s: DecryptSecrets succeeded", test.name)
+		}
+	}
+}
+
+// clearCredentialEnv points the default files into an empty directory and unsets the credential variables
+func clearCredentialEnv(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	for _, name := range []string{EnvUserName, EnvPassword, EnvProfile, EnvCredentialsFile, EnvSecretsFile, EnvSecretsPassphrase} {
+		t.Setenv(name, "")
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, filename string, data []byte) string {
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+const testProfiles = `# comment
+[default]
+username = me@example.com
+password = secret
+
+[profile reports]
+; comment
+USERNAME = reports@example.com
+password= p=ss
+
+[nopassword]
+username = other@example.com
+`
+
+func TestEnvProvider(t *testing.T) {
+	clearCredentialEnv(t)
+	if _, err := (&EnvProvider{}).Retrieve(); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Retrieve without variables = %v, want ErrNoCredentials", err)
+	}
+	t.Setenv(EnvUserName, "me@example.com")
+	if _, err := (&EnvProvider{}).Retrieve(); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Retrieve without password = %v, want ErrNoCredentials", err)
+	}
+	t.Setenv(EnvPassword, "secret")
+	creds, err := (&EnvProvider{}).Retrieve()
+	if err != nil || creds != (Credentials{UserName: "me@example.com", Password: "secret", Source: "env"}) {
+		t.Errorf("Retrieve = %+v, %v", creds, err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := clearCredentialEnv(t)
+	filename := writeFile(t, filepath.Join(dir, "credentials"), []byte(testProfiles))
+	tests := []struct {
+		profile  string
+		envName  string
+		user     string
+		password string
+		wantErr  error
+	}{
+		{"", "", "me@example.com", "secret", nil},
+		{"reports", "", "reports@example.com", "p=ss", nil},
+		{"", "reports", "reports@example.com", "p=ss", nil},
+		{"missing", "", "", "", ErrNoCredentials},
+		{"nopassword", "", "", "", errAny},
+	}
+	for _, test := range tests {
+		t.Setenv(EnvProfile, test.envName)
+		creds, err := (&FileProvider{Filename: filename, Profile: test.profile}).Retrieve()
+		if !matchError(err, test.wantErr) {
+			t.Errorf("profile %q: error %v, want %v", test.profile, err, test.wantErr)
+			continue
+		}
+		if err == nil && (creds.UserName != test.user || creds.Password != test.password || creds.Source != "file:"+filename) {
+			t.Errorf("profile %q: %+v", test.profile, creds)
+		}
+	}
+
+	t.Setenv(EnvProfile, "")
+	if _, err := (&FileProvider{Filename: filepath.Join(dir, "missing")}).Retrieve(); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("missing file: %v, want ErrNoCredentials", err)
+	}
+	t.Setenv(EnvCredentialsFile, filename)
+	if creds, err := (&FileProvider{}).Retrieve(); err != nil || creds.UserName != "me@example.com" {
+		t.Errorf("file of %s: %+v, %v", EnvCredentialsFile, creds, err)
+	}
+}
+
+func TestEncryptedFileProvider(t *testing.T) {
+	dir := clearCredentialEnv(t)
+	encrypted, err := EncryptSecrets([]byte(testProfiles), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := writeFile(t, filepath.Join(dir, "secrets.enc"), encrypted)
+
+	creds, err := (&EncryptedFileProvider{Filename: filename, Profile: "reports", Passphrase: "passphrase"}).Retrieve()
+	if err != nil || creds.UserName != "reports@example.com" || creds.Source != "secrets:"+filename {
+		t.Errorf("Retrieve = %+v, %v", creds, err)
+	}
+	if _, err := (&EncryptedFileProvider{Filename: filename}).Retrieve(); err == nil || errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Retrieve without passphrase = %v, want an error", err)
+	}
+	if _, err := (&EncryptedFileProvider{Filename: filename, Passphrase: "wrong"}).Retrieve(); err == nil || errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Retrieve with a wrong passphrase = %v, want an error", err)
+	}
+	if _, err := (&EncryptedFileProvider{Filename: filepath.Join(dir, "missing"), Passphrase: "passphrase"}).Retrieve(); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("missing file: %v, want ErrNoCredentials", err)
+	}
+	t.Setenv(EnvSecretsFile, filename)
+	t.Setenv(EnvSecretsPassphrase, "passphrase")
+	if creds, err := (&EncryptedFileProvider{}).Retrieve(); err != nil || creds.UserName != "me@example.com" {
+		t.Errorf("file and passphrase of the environment: %+v, %v", creds, err)
+	}
+}
+
+type testProvider struct {
+	creds Credentials
+	err   error
+	calls *int
+}
+
+func (p testProvider) Retrieve() (Credentials, error) {
+	*p.calls++
+	return p.creds, p.err
+}
+
+func TestChainProvider(t *testing.T) {
+	failed := errors.New("failed")
+	found := Credentials{UserName: "me@example.com", Password: "secret", Source: "test"}
+	tests := []struct {
+		name      string
+		results   []error
+		wantErr   error
+		wantCalls int
+	}{
+		{"first found", []error{nil, nil}, nil, 1},
+		{"falls through providers without credentials", []error{ErrNoCredentials, ErrNoCredentials, nil}, nil, 3},
+		{"stops at the first error", []error{ErrNoCredentials, failed, nil}, failed, 2},
+		{"error after credentials is not reached", []error{nil, failed}, nil, 1},
+		{"nothing found", []error{ErrNoCredentials, ErrNoCredentials}, ErrNoCredentials, 2},
+		{"no providers", nil, ErrNoCredentials, 0},
+	}
+	for _, test := range tests {
+		calls := 0
+		providers := make([]CredentialProvider, len(test.results))
+		for i, err := range test.results {
+			p := testProvider{err: err, calls: &calls}
+			if err == nil {
+				p.creds = found
+			}
+			providers[i] = p
+		}
+		creds, err := NewChainProvider(providers...).Retrieve()
+		if !errors.Is(err, test.wantErr) || (err == nil) != (test.wantErr == nil) {
+			t.Errorf("%s: error %v, want %v", test.name, err, test.wantErr)
+		}
+		if err == nil && creds != found {
+			t.Errorf("%s: credentials %+v", test.name, creds)
+		}
+		if calls != test.wantCalls {
+			t.Errorf("%s: %d providers called, want %d", test.name, calls, test.wantCalls)
+		}
+	}
+}
+
+func TestDefaultCredentialChain(t *testing.T) {
+	dir := clearCredentialEnv(t)
+	if _, err := DefaultCredentialChain().Retrieve(); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Retrieve without credentials = %v, want ErrNoCredentials", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".boilingdata"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, ".boilingdata", "credentials"), []byte(testProfiles))
+	if creds, err := DefaultCredentialChain().Retrieve(); err != nil || creds.Source != "file:"+filepath.Join(dir, ".boilingdata", "credentials") {
+		t.Errorf("Retrieve = %+v, %v, want the default credentials file", creds, err)
+	}
+	t.Setenv(EnvUserName, "env@example.com")
+	t.Setenv(EnvPassword, "secret")
+	if creds, err := DefaultCredentialChain().Retrieve(); err != nil || creds.Source != "env" {
+		t.Errorf("Retrieve = %+v, %v, want the environment first", creds, err)
+	}
+}
+
+// errAny matches any error but ErrNoCredentials
+var errAny = errors.New("any error")
+
+func matchError(err, want error) bool {
+	if want == errAny {
+		return err != nil && !errors.Is(err, ErrNoCredentials)
+	}
+	if want == nil {
+		return err == nil
+	}
+	return errors.Is(err, want)
+}
@@ -0,0 +1,137 @@
+package boilingdata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression of five fields, minute hour day-of-month month day-of-week.
+// Fields are *, numbers, ranges a-b, lists a,b and steps */n or a-b/n. Day of week is 0-7, 0 and 7 are Sunday.
+// When both day of month and day of week are restricted, a day matching either runs, like cron does.
+// @hourly, @daily, @weekly, @monthly and @yearly are accepted too.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+	c := &Cron{}
+	var err error
+	if c.minute, err = cronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %v", err)
+	}
+	if c.hour, err = cronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %v", err)
+	}
+	if c.dom, err = cronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %v", err)
+	}
+	if c.month, err = cronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %v", err)
+	}
+	if c.dow, err = cronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %v", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// cronField returns the bit set of the values of a field
+func cronField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				// a/n means from a to the end
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Match reports whether the expression runs in the minute of t
+func (c *Cron) Match(t time.Time) bool {
+	return c.minute&(1<<uint(t.Minute())) != 0 && c.hour&(1<<uint(t.Hour())) != 0 &&
+		c.month&(1<<uint(t.Month())) != 0 && c.dayMatch(t)
+}
+
+func (c *Cron) dayMatch(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the first minute after t the expression runs in, the zero time if there is none within 5 years
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatch(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
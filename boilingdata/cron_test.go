@@ -0,0 +1,91 @@
+package boilingdata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * 32 * *",
+		"* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "1-a * * * *", "@every",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronMatch(t *testing.T) {
+	tests := []struct {
+		expr  string
+		time  string
+		match bool
+	}{
+		{"* * * * *", "2024-04-20T10:07:00Z", true},
+		{"7 10 * * *", "2024-04-20T10:07:00Z", true},
+		{"7 10 * * *", "2024-04-20T10:08:00Z", false},
+		{"0-10 * * * *", "2024-04-20T10:10:00Z", true},
+		{"0-10 * * * *", "2024-04-20T10:11:00Z", false},
+		{"*/15 * * * *", "2024-04-20T10:45:00Z", true},
+		{"*/15 * * * *", "2024-04-20T10:46:00Z", false},
+		{"10-30/10 * * * *", "2024-04-20T10:20:00Z", true},
+		{"10-30/10 * * * *", "2024-04-20T10:40:00Z", false},
+		{"5/20 * * * *", "2024-04-20T10:45:00Z", true},
+		{"1,2,58 * * * *", "2024-04-20T10:58:00Z", true},
+		{"0 0 * * 6", "2024-04-20T00:00:00Z", true},    // Saturday
+		{"0 0 * * 0", "2024-04-21T00:00:00Z", true},    // Sunday as 0
+		{"0 0 * * 7", "2024-04-21T00:00:00Z", true},    // Sunday as 7
+		{"0 0 * * 1-5", "2024-04-21T00:00:00Z", false}, // weekdays only
+		{"0 0 1 * *", "2024-04-01T00:00:00Z", true},
+		{"0 0 1 * *", "2024-04-02T00:00:00Z", false},
+		{"0 0 * 2 *", "2024-04-01T00:00:00Z", false},
+		// Day of month and day of week both restricted, either matches
+		{"0 0 1 * 6", "2024-04-20T00:00:00Z", true},
+		{"0 0 1 * 6", "2024-04-01T00:00:00Z", true},
+		{"0 0 1 * 6", "2024-04-02T00:00:00Z", false},
+		// Only one restricted, it must match
+		{"0 0 1 * *", "2024-04-20T00:00:00Z", false},
+		{"0 0 */2 * 6", "2024-04-21T00:00:00Z", false},
+		{"@daily", "2024-04-20T00:00:00Z", true},
+		{"@hourly", "2024-04-20T10:01:00Z", false},
+	}
+	for _, test := range tests {
+		cron, err := ParseCron(test.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", test.expr, err)
+		}
+		at, _ := time.Parse(time.RFC3339, test.time)
+		if got := cron.Match(at); got != test.match {
+			t.Errorf("%q matches %s = %v, want %v", test.expr, test.time, got, test.match)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"* * * * *", "2024-04-20T10:07:30Z", "2024-04-20T10:08:00Z"},
+		{"*/15 * * * *", "2024-04-20T10:45:00Z", "2024-04-20T11:00:00Z"},
+		{"30 9 * * *", "2024-04-20T10:00:00Z", "2024-04-21T09:30:00Z"},
+		{"0 0 * * 1", "2024-04-20T10:00:00Z", "2024-04-22T00:00:00Z"},
+		{"0 0 31 * *", "2024-04-20T10:00:00Z", "2024-05-31T00:00:00Z"},
+		{"0 0 29 2 *", "2024-04-20T10:00:00Z", "2028-02-29T00:00:00Z"},
+		{"0 0 1 1 *", "2024-12-31T23:59:00Z", "2025-01-01T00:00:00Z"},
+		{"0 12 13 * 5", "2024-04-20T10:00:00Z", "2024-04-26T12:00:00Z"},
+		{"0 0 30 2 *", "2024-04-20T10:00:00Z", "0001-01-01T00:00:00Z"},
+	}
+	for _, test := range tests {
+		cron, err := ParseCron(test.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", test.expr, err)
+		}
+		from, _ := time.Parse(time.RFC3339, test.from)
+		if got := cron.Next(from).Format(time.RFC3339); got != test.want {
+			t.Errorf("%q next after %s = %s, want %s", test.expr, test.from, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package boilingdata
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCursorNotFound is returned for unknown or expired cursors
+var ErrCursorNotFound = errors.New("cursor not found or expired")
+
+// CursorConfig sets how long results paged with cursors are kept and when they are spilled to disk
+type CursorConfig struct {
+	// TTLSeconds since the last page was read, 600 by default
+	TTLSeconds int `json:"ttlSeconds"`
+	// MaxMemoryBytes of results kept in memory, larger results are written to SpillDir. 256MB by default
+	MaxMemoryBytes int64 `json:"maxMemoryBytes"`
+	// SpillDir is boilingdata-cursors in the temp directory by default. Spill files are named
+	// cursor-<id>.json, other files in the directory are left alone.
+	SpillDir string `json:"spillDir"`
+}
+
+// CursorStore keeps results of queries so that they can be read page by page
+type CursorStore struct {
+	ttl            time.Duration
+	maxMemoryBytes int64
+	spillDir       string
+	mu             sync.Mutex
+	entries        map[string]*cursorEntry
+	memoryBytes    int64
+}
+
+type cursorEntry struct {
+	user     string
+	pageSize int
+	expires  time.Time
+	// result is nil when the result was spilled to file
+	result *Result
+	file   string
+	size   int64
+}
+
+func NewCursorStore(cfg CursorConfig) (*CursorStore, error) {
+	s := &CursorStore{
+		ttl:            time.Duration(cfg.TTLSeconds) * time.Second,
+		maxMemoryBytes: cfg.MaxMemoryBytes,
+		spillDir:       cfg.SpillDir,
+		entries:        make(map[string]*cursorEntry),
+	}
+	if s.ttl <= 0 {
+		s.ttl = 10 * time.Minute
+	}
+	if s.maxMemoryBytes <= 0 {
+		s.maxMemoryBytes = 256 << 20
+	}
+	if s.spillDir == "" {
+		s.spillDir = filepath.Join(os.TempDir(), "boilingdata-cursors")
+	}
+	if err := os.MkdirAll(s.spillDir, 0700); err != nil {
+		return nil, err
+	}
+	// Spill files of an earlier run can not be read anymore
+	files, err := filepath.Glob(filepath.Join(s.spillDir, "cursor-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+var cursorsMu sync.RWMutex
+var cursorStore *CursorStore
+
+// SetCursorStore sets the cursor store used by the server, nil disables paging
+func SetCursorStore(s *CursorStore) {
+	cursorsMu.Lock()
+	defer cursorsMu.Unlock()
+	cursorStore = s
+}
+
+// GetCursorStore returns the cursor store of the server, nil when paging is disabled
+func GetCursorStore() *CursorStore {
+	cursorsMu.RLock()
+	defer cursorsMu.RUnlock()
+	return cursorStore
+}
+
+// Page is a page of a result, NextCursor is empty on the last page
+type Page struct {
+	*Result
+	Offset     int    `json:"offset"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// FirstPage returns the first pageSize rows of the result. When there are more rows, the result is kept
+// for the user and the page has the cursor of the next page.
+func (s *CursorStore) FirstPage(user string, result *Result, pageSize int) (Page, error) {
+	page := Page{Result: result.Page(0, pageSize), Total: len(result.Rows)}
+	if pageSize <= 0 || len(result.Rows) <= pageSize {
+		return page, nil
+	}
+	entry := &cursorEntry{user: user, pageSize: pageSize, result: result, size: resultSize(result)}
+	id := newRequestID()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeExpired()
+	if s.memoryBytes+entry.size > s.maxMemoryBytes {
+		if err := s.spill(id, entry); err != nil {
+			return Page{}, err
+		}
+	} else {
+		s.memoryBytes += entry.size
+	}
+	entry.expires = time.Now().Add(s.ttl)
+	s.entries[id] = entry
+	page.NextCursor = encodeCursor(id, pageSize)
+	return page, nil
+}
+
+// Next returns the page of the cursor, pageSize overrides the page size of the cursor when positive
+func (s *CursorStore) Next(user string, cursor string, pageSize int) (Page, error) {
+	id, offset, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeExpired()
+	entry, ok := s.entries[id]
+	if !ok || entry.user != user {
+		return Page{}, ErrCursorNotFound
+	}
+	if pageSize <= 0 {
+		pageSize = entry.pageSize
+	}
+	result := entry.result
+	if result == nil {
+		if result, err = readSpill(entry.file); err != nil {
+			return Page{}, err
+		}
+	}
+	page := Page{Result: result.Page(offset, pageSize), Offset: min(offset, len(result.Rows)), Total: len(result.Rows)}
+	if offset+pageSize < len(result.Rows) {
+		page.NextCursor = encodeCursor(id, offset+pageSize)
+		entry.expires = time.Now().Add(s.ttl)
+	} else {
+		s.remove(id)
+	}
+	return page, nil
+}
+
+func (s *CursorStore) spill(id string, entry *cursorEntry) error {
+	data, err := json.Marshal(entry.result)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(s.spillDir, "cursor-"+id+".json")
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return fmt.Errorf("could not spill result to disk: %v", err)
+	}
+	entry.file = file
+	entry.result = nil
+	return nil
+}
+
+func readSpill(file string) (*Result, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var result Result
+	if err := unmarshalNumbers(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *CursorStore) remove(id string) {
+	entry := s.entries[id]
+	delete(s.entries, id)
+	if entry.file != "" {
+		if err := os.Remove(entry.file); err != nil && !os.IsNotExist(err) {
+			log.Println("Could not remove spill file: " + err.Error())
+		}
+	} else {
+		s.memoryBytes -= entry.size
+	}
+}
+
+func (s *CursorStore) removeExpired() {
+	now := time.Now()
+	for id, entry := range s.entries {
+		if now.After(entry.expires) {
+			s.remove(id)
+		}
+	}
+}
+
+// Cursors are the id of the kept result and the offset of the page
+func encodeCursor(id string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id + ":" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (string, int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrCursorNotFound
+	}
+	id, offsetText, ok := strings.Cut(string(data), ":")
+	offset, err := strconv.Atoi(offsetText)
+	if !ok || err != nil || offset < 0 {
+		return "", 0, ErrCursorNotFound
+	}
+	return id, offset, nil
+}
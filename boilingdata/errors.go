@@ -0,0 +1,82 @@
+package boilingdata
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pavi6691/go-boilingdata/wsclient"
+)
+
+// Kinds of query errors, test with errors.Is(err, ErrThrottled)
+var (
+	// ErrTransport is a failure of the websocket connection
+	ErrTransport = errors.New("transport error")
+	// ErrThrottled is returned when BoilingData rejected the request because of too many requests
+	ErrThrottled = errors.New("throttled")
+	// ErrServer is a transient failure on the server side
+	ErrServer = errors.New("server error")
+	// ErrQuery is a failure of the query itself, e.g. a syntax error, sending it again does not help
+	ErrQuery = errors.New("query error")
+)
+
+// QueryError is returned by queries that failed after all attempts
+type QueryError struct {
+	// Kind is one of ErrTransport, ErrThrottled, ErrServer or ErrQuery
+	Kind error
+	// RequestID of the last attempt
+	RequestID string
+	Attempts  int
+	Err       error
+}
+
+func newQueryError(requestID string, err error) *QueryError {
+	return &QueryError{Kind: classify(err), RequestID: requestID, Attempts: 1, Err: err}
+}
+
+// Error returns the message of the server for failed queries, transport errors describe the websocket failure
+func (e *QueryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *QueryError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// IsRetryable reports whether a query that failed with err may succeed when sent again.
+// Transport, throttling and transient server errors are retryable.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrTransport) || errors.Is(err, ErrThrottled) || errors.Is(err, ErrServer)
+}
+
+var throttledMessages = []string{"too many requests", "throttl", "rate exceeded", "limit exceeded"}
+var transientMessages = []string{"internal server error", "service unavailable", "timed out", "timeout", "try again"}
+
+// classify returns the kind of an error of the websocket client
+func classify(err error) error {
+	var transportErr *wsclient.TransportError
+	if errors.As(err, &transportErr) {
+		return ErrTransport
+	}
+	var serverErr *wsclient.ServerError
+	if errors.As(err, &serverErr) {
+		message := strings.ToLower(serverErr.Message)
+		for _, m := range throttledMessages {
+			if strings.Contains(message, m) {
+				return ErrThrottled
+			}
+		}
+		for _, m := range transientMessages {
+			if strings.Contains(message, m) {
+				return ErrServer
+			}
+		}
+		if strings.HasPrefix(serverErr.Message, "Error parsing JSON") {
+			return ErrServer
+		}
+	}
+	return ErrQuery
+}
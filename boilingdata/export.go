@@ -0,0 +1,110 @@
+package boilingdata
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Formats results can be written in
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// ValidFormat reports an error for formats Write does not support
+func ValidFormat(format string) error {
+	switch format {
+	case FormatJSON, FormatNDJSON, FormatCSV:
+		return nil
+	}
+	return fmt.Errorf("format must be %s, %s or %s", FormatJSON, FormatNDJSON, FormatCSV)
+}
+
+// Write writes the result as JSON like the /query response, as newline delimited JSON rows or as CSV
+// with a header row. Columns of rows are written in schema order.
+func (r *Result) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(r, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatNDJSON:
+		columns := r.Columns()
+		for _, row := range r.Rows {
+			line, err := marshalRow(columns, row)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		columns := r.Columns()
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+		record := make([]string, len(columns))
+		for _, row := range r.Rows {
+			for i, column := range columns {
+				value, err := csvValue(row[column])
+				if err != nil {
+					return err
+				}
+				record[i] = value
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return ValidFormat(format)
+}
+
+// marshalRow marshals a row as a JSON object with its keys in column order
+func marshalRow(columns []string, row map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(row[column])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// csvValue writes NULL as an empty field and nested values as JSON
+func csvValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
@@ -0,0 +1,293 @@
+package boilingdata
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+const (
+	HistoryStatusSuccess = "success"
+	HistoryStatusError   = "error"
+)
+
+// HistoryEntry records a query run through Instance.Execute or Instance.Query
+type HistoryEntry struct {
+	ID             string           `json:"id"`
+	User           string           `json:"user"`
+	SQL            string           `json:"sql"`
+	Tags           []models.Tag     `json:"tags"`
+	ReadCache      models.CacheMode `json:"readCache"`
+	RequestID      string           `json:"requestId"`
+	Start          time.Time        `json:"start"`
+	End            time.Time        `json:"end"`
+	Status         string           `json:"status"`
+	Rows           int              `json:"rows"`
+	Bytes          int64            `json:"bytes"`
+	Batches        int              `json:"batches"`
+	Cache          CacheStats       `json:"cache"`
+	ClientCacheHit bool             `json:"clientCacheHit"`
+	Retries        int              `json:"retries"`
+	Error          string           `json:"error,omitempty"`
+}
+
+// HistoryFilter selects history entries, zero fields match everything
+type HistoryFilter struct {
+	User   string
+	Since  time.Time
+	Until  time.Time
+	Status string
+	// Limit is the maximum number of entries returned, newest first
+	Limit int
+}
+
+func (f HistoryFilter) match(entry HistoryEntry) bool {
+	return (f.User == "" || entry.User == f.User) &&
+		(f.Since.IsZero() || !entry.Start.Before(f.Since)) &&
+		(f.Until.IsZero() || entry.Start.Before(f.Until)) &&
+		(f.Status == "" || entry.Status == f.Status)
+}
+
+// HistoryStore keeps the history of queries, it must be safe for concurrent use
+type HistoryStore interface {
+	Record(entry HistoryEntry) error
+	// List returns the entries matching the filter, newest first
+	List(filter HistoryFilter) ([]HistoryEntry, error)
+	Get(id string) (HistoryEntry, bool, error)
+}
+
+var historyMu sync.RWMutex
+var historyStore HistoryStore
+
+// SetHistoryStore sets where queries are recorded, nil disables the history
+func SetHistoryStore(store HistoryStore) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	historyStore = store
+}
+
+// History returns the history store, nil when the history is disabled
+func History() HistoryStore {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+	return historyStore
+}
+
+// recordHistory records a query that started at start with its result or error
+func (instance *Instance) recordHistory(start time.Time, payload models.Payload, result *Result, err error) {
+	store := History()
+	if store == nil {
+		return
+	}
+	entry := HistoryEntry{
+		ID:        newRequestID(),
+		User:      instance.Auth.UserName(),
+		SQL:       payload.SQL,
+		Tags:      payload.Tags,
+		ReadCache: payload.ReadCache,
+		RequestID: payload.RequestID,
+		Start:     start.UTC(),
+		End:       time.Now().UTC(),
+		Status:    HistoryStatusSuccess,
+	}
+	if err != nil {
+		entry.Status = HistoryStatusError
+		entry.Error = err.Error()
+	}
+	if result != nil {
+		entry.RequestID = result.RequestID
+		entry.Rows = len(result.Rows)
+		entry.Cache = result.Meta.Cache
+		entry.ClientCacheHit = result.Meta.ClientCacheHit
+		entry.Retries = result.Meta.Retries
+		entry.Bytes = result.Meta.Bytes
+		entry.Batches = result.Meta.Batches
+	}
+	if err := store.Record(entry); err != nil {
+		log.Println("Could not record query history: " + err.Error())
+	}
+}
+
+// DefaultHistoryMaxEntries is the number of queries kept by the history file of the server
+const DefaultHistoryMaxEntries = 10000
+
+// FileHistoryStore keeps the history as JSON lines in a file
+type FileHistoryStore struct {
+	filename string
+	// maxEntries is the number of newest entries kept, 0 keeps all
+	maxEntries int
+	// lines in the file
+	lines int
+	mu    sync.Mutex
+}
+
+// NewFileHistoryStore opens the history file, keeping the newest maxEntries entries, all if 0.
+// The file is compacted once it has a tenth more entries than that.
+func NewFileHistoryStore(filename string, maxEntries int) (*FileHistoryStore, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	s := &FileHistoryStore{filename: filename, maxEntries: maxEntries}
+	lines, err := s.readLines()
+	if err != nil {
+		return nil, err
+	}
+	s.lines = len(lines)
+	if err := s.compact(lines); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// DefaultHistoryFile returns ~/.boilingdata/history.jsonl
+func DefaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "history.jsonl"
+	}
+	return filepath.Join(home, ".boilingdata", "history.jsonl")
+}
+
+func (s *FileHistoryStore) Record(entry HistoryEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(s.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	file.Close()
+	if err != nil {
+		return err
+	}
+	s.lines++
+	if s.maxEntries > 0 && s.lines > s.maxEntries+s.maxEntries/10 {
+		lines, err := s.readLines()
+		if err != nil {
+			return err
+		}
+		return s.compact(lines)
+	}
+	return nil
+}
+
+// readLines returns the lines of the file, the caller holds mu
+func (s *FileHistoryStore) readLines() ([][]byte, error) {
+	file, err := os.Open(s.filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	lines := [][]byte{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	return lines, scanner.Err()
+}
+
+// compact rewrites the file with the newest maxEntries lines if it has more, the caller holds mu
+func (s *FileHistoryStore) compact(lines [][]byte) error {
+	if s.maxEntries <= 0 || len(lines) <= s.maxEntries {
+		return nil
+	}
+	lines = lines[len(lines)-s.maxEntries:]
+	tmp := s.filename + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, line := range lines {
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.filename); err != nil {
+		return err
+	}
+	s.lines = len(lines)
+	return nil
+}
+
+func (s *FileHistoryStore) List(filter HistoryFilter) ([]HistoryEntry, error) {
+	entries := []HistoryEntry{}
+	err := s.scan(func(entry HistoryEntry) bool {
+		if filter.match(entry) {
+			entries = append(entries, entry)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The file is in insertion order, newest first is the reverse
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+func (s *FileHistoryStore) Get(id string) (HistoryEntry, bool, error) {
+	var found HistoryEntry
+	ok := false
+	err := s.scan(func(entry HistoryEntry) bool {
+		if entry.ID == id {
+			found, ok = entry, true
+			return false
+		}
+		return true
+	})
+	return found, ok, err
+}
+
+// scan calls fn for every entry in the file until it returns false, lines that can not be parsed are skipped
+func (s *FileHistoryStore) scan(fn func(HistoryEntry) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.Open(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if !fn(entry) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
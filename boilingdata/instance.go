@@ -1,14 +1,17 @@
 package boilingdata
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/golang-jwt/jwt/v4"
 	cmap "github.com/orcaman/concurrent-map"
-	"github.com/pavi6691/go-boilingdata/constants"
 	"github.com/pavi6691/go-boilingdata/models"
 	"github.com/pavi6691/go-boilingdata/wsclient"
 )
@@ -16,6 +19,11 @@ import (
 type Instance struct {
 	Wsc  *wsclient.WSSClient
 	Auth *Auth
+	// Cache is the client side result cache, nil when disabled
+	Cache   ResultCache
+	flights *flightGroup
+	// connectMu serializes connecting, concurrent queries would otherwise all sign and dial
+	connectMu *sync.Mutex
 }
 
 var queryServiceMap = cmap.New()
@@ -53,52 +61,155 @@ func GetInstanceByToken(token string) (*Instance, error) {
 	return qs.(*Instance), nil
 }
 
+func newInstance(auth *Auth) *Instance {
+	cfg := auth.conf()
+	return &Instance{
+		Wsc:       wsclient.NewWSSClient(cfg.WssURL, time.Duration(cfg.IdleTimeoutMinutes), nil),
+		Auth:      auth,
+		Cache:     newResultCache(cfg.ResultCache, auth.userName),
+		flights:   newFlightGroup(),
+		connectMu: &sync.Mutex{},
+	}
+}
+
 func GetInstance(userName string, password string) *Instance {
 	muLock.Lock()
 	defer muLock.Unlock()
 	qs, ok := queryServiceMap.Get(userName)
 	if !ok {
-		wsclient := wsclient.NewWSSClient(constants.WssUrl, 0, nil)
-		qs = &Instance{Wsc: wsclient, Auth: &Auth{userName: userName, password: password}}
+		qs = newInstance(&Auth{userName: userName, password: password})
 		queryServiceMap.Set(userName, qs)
 	}
 	return qs.(*Instance)
 }
 
+// Login authenticates the credentials with a fresh Auth and only when successful attaches it to the
+// instance of the user. A failed attempt leaves any existing session of the user untouched.
+// sourceIP is the client the login came from, if known, and is recorded in auth events.
+func Login(userName string, password string, sourceIP string) (*Instance, error) {
+	auth := &Auth{userName: userName, password: password, sourceIP: sourceIP}
+	if _, err := auth.Authenticate(); err != nil {
+		countLoginFailure()
+		return nil, err
+	}
+	countLoginSuccess()
+	muLock.Lock()
+	defer muLock.Unlock()
+	qs, ok := queryServiceMap.Get(userName)
+	if ok {
+		qs.(*Instance).Auth = auth
+	} else {
+		qs = newInstance(auth)
+		queryServiceMap.Set(userName, qs)
+	}
+	return qs.(*Instance), nil
+}
+
+// GetIAMInstance returns the instance registered under name, signing the websocket request with the
+// AWS credentials directly instead of logging in through Cognito. An instance already registered under
+// name with other credentials gets the new ones and reconnects with them on the next query.
+func GetIAMInstance(name string, iamCredentials *credentials.Credentials) *Instance {
+	muLock.Lock()
+	defer muLock.Unlock()
+	qs, ok := queryServiceMap.Get(name)
+	if !ok {
+		qs = newInstance(NewIAMAuth(name, iamCredentials))
+		queryServiceMap.Set(name, qs)
+		return qs.(*Instance)
+	}
+	instance := qs.(*Instance)
+	if instance.Auth.iamCredentials != iamCredentials {
+		auth := NewIAMAuth(name, iamCredentials)
+		auth.config = instance.Auth.config
+		instance.Auth = auth
+		instance.Wsc.Close()
+	}
+	return instance
+}
+
 func RemoveUser(userName string) {
 	queryServiceMap.Remove(userName)
 }
 
+// Logout closes the websocket of the user and removes its instance
+func Logout(userName string) {
+	qs, ok := queryServiceMap.Get(userName)
+	if !ok {
+		return
+	}
+	instance := qs.(*Instance)
+	instance.Wsc.Close()
+	RemoveUser(userName)
+	instance.Auth.emitEvent(AuthFlowLogout, OutcomeSuccess, nil)
+}
+
 func (instance *Instance) Query(payloadMessage []byte) (*models.Response, error) {
+	var payload models.Payload
+	if err := json.Unmarshal(payloadMessage, &payload); err != nil {
+		log.Println("error unmarshalling Payload : " + err.Error())
+		return &models.Response{}, fmt.Errorf("error unmarshalling Payload : " + err.Error())
+	}
+	result, err := instance.Execute(context.Background(), Query{
+		SQL:       payload.SQL,
+		Tags:      payload.Tags,
+		ReadCache: payload.ReadCache,
+		RequestID: payload.RequestID,
+	})
+	if err != nil {
+		return &models.Response{}, err
+	}
+	return result.Response(), nil
+}
+
+// connect authenticates and connects the websocket if it is closed
+func (instance *Instance) connect() error {
+	instance.connectMu.Lock()
+	defer instance.connectMu.Unlock()
 	// If web socket is closed, in case of timeout/user signout/os intruptions etc
 	if instance.Wsc.IsWebSocketClosed() {
 		idToken, err := instance.Auth.Authenticate()
 		if err != nil {
-			return &models.Response{}, fmt.Errorf("Error : " + err.Error())
+			return fmt.Errorf("Error : " + err.Error())
 		}
 		header, err := instance.Auth.GetSignedWssHeader(idToken)
 		if err != nil {
-			return &models.Response{}, fmt.Errorf("Error Signing wssUrl: " + err.Error())
+			return fmt.Errorf("Error Signing wssUrl: " + err.Error())
 		}
 		instance.Wsc.SignedHeader = header
 		instance.Wsc.Connect()
 		if instance.Wsc.IsWebSocketClosed() {
-			return &models.Response{}, fmt.Errorf(instance.Wsc.Error)
+			return newQueryError("", &wsclient.TransportError{Op: "connect to", Err: errors.New(instance.Wsc.Error)})
 		}
 	}
-	var payload models.Payload
-	if err := json.Unmarshal(payloadMessage, &payload); err != nil {
-		log.Println("error unmarshalling Payload : " + err.Error())
-		return &models.Response{}, fmt.Errorf("error unmarshalling Payload : " + err.Error())
+	return nil
+}
+
+// execute sends the payload and waits for its response, calling onParts with the parts received so far
+// whenever a part arrives if not nil
+func (instance *Instance) execute(ctx context.Context, payload models.Payload, onParts func([]*models.Response)) (*models.Response, error) {
+	if payload.ReadCache == "" {
+		payload.ReadCache = models.CacheNone
+	}
+	if err := payload.ReadCache.Validate(); err != nil {
+		return &models.Response{}, err
+	}
+	if err := instance.connect(); err != nil {
+		return &models.Response{}, err
+	}
+	payloadMessage, err := json.Marshal(payload)
+	if err != nil {
+		return &models.Response{}, fmt.Errorf("error marshalling Payload : " + err.Error())
 	}
 	instance.Wsc.SendMessage(payloadMessage, payload)
-	response, err := instance.Wsc.GetResponseSync(payload.RequestID)
-	if response.Data == nil || err != nil {
-		errorMessage := ""
-		if err != nil {
-			errorMessage = err.Error()
-		}
-		return &models.Response{}, fmt.Errorf("Internal Server Error, could not read messages from websocket -> " + errorMessage)
+	response, err := instance.Wsc.WaitResponse(ctx, payload.RequestID, onParts)
+	if ctx.Err() != nil {
+		return &models.Response{}, ctx.Err()
+	}
+	if err != nil {
+		return &models.Response{}, newQueryError(payload.RequestID, err)
+	}
+	if response.Data == nil {
+		return &models.Response{}, newQueryError(payload.RequestID, wsclient.ErrNoResult)
 	}
 	return response, nil
 }
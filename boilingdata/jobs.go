@@ -0,0 +1,168 @@
+package boilingdata
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a query running in the background
+type Job struct {
+	ID        string     `json:"id"`
+	User      string     `json:"user"`
+	SQL       string     `json:"sql"`
+	Status    string     `json:"status"`
+	Progress  Progress   `json:"progress"`
+	Rows      int        `json:"rows,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Submitted time.Time  `json:"submitted"`
+	Finished  *time.Time `json:"finished,omitempty"`
+	// Expires is when the finished job and its result are removed
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+type job struct {
+	info   Job
+	result *Result
+	cancel context.CancelFunc
+}
+
+// JobManager runs queries in the background and keeps finished jobs with their results for the retention
+type JobManager struct {
+	retention time.Duration
+	mu        sync.Mutex
+	jobs      map[string]*job
+}
+
+// NewJobManager keeps finished jobs for retention, one hour if not set
+func NewJobManager(retention time.Duration) *JobManager {
+	if retention <= 0 {
+		retention = time.Hour
+	}
+	return &JobManager{retention: retention, jobs: make(map[string]*job)}
+}
+
+var jobsMu sync.RWMutex
+var jobManager *JobManager
+
+// SetJobManager sets the job manager used by the server, nil disables jobs
+func SetJobManager(m *JobManager) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	jobManager = m
+}
+
+// GetJobManager returns the job manager of the server, nil when jobs are disabled
+func GetJobManager() *JobManager {
+	jobsMu.RLock()
+	defer jobsMu.RUnlock()
+	return jobManager
+}
+
+// Submit starts executing the query on the instance and returns the job right away
+func (m *JobManager) Submit(instance *Instance, q Query) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		info: Job{
+			ID:        newRequestID(),
+			User:      instance.Auth.UserName(),
+			SQL:       q.SQL,
+			Status:    JobRunning,
+			Submitted: time.Now().UTC(),
+		},
+		cancel: cancel,
+	}
+	q.onProgress = func(progress Progress) {
+		m.mu.Lock()
+		j.info.Progress = progress
+		m.mu.Unlock()
+	}
+	m.mu.Lock()
+	m.removeExpired()
+	m.jobs[j.info.ID] = j
+	info := j.info
+	m.mu.Unlock()
+
+	go func() {
+		result, err := instance.Execute(ctx, q)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		finished := time.Now().UTC()
+		expires := finished.Add(m.retention)
+		j.info.Finished = &finished
+		j.info.Expires = &expires
+		j.result = result
+		switch {
+		case j.info.Status == JobCancelled:
+		case err != nil:
+			j.info.Status = JobFailed
+			j.info.Error = err.Error()
+		default:
+			j.info.Status = JobSucceeded
+			j.info.Rows = len(result.Rows)
+			j.info.Progress = completeProgress(result)
+		}
+		cancel()
+	}()
+	return info
+}
+
+// Get returns the job, if it exists and was not removed after the retention
+func (m *JobManager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeExpired()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return j.info, true
+}
+
+// Result returns the job with its result, which is nil unless the job succeeded
+func (m *JobManager) Result(id string) (Job, *Result, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeExpired()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, nil, false
+	}
+	if j.info.Status != JobSucceeded {
+		return j.info, nil, true
+	}
+	return j.info, j.result, true
+}
+
+// Cancel cancels a running job, a finished job is removed with its result
+func (m *JobManager) Cancel(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	if j.info.Status == JobRunning {
+		j.info.Status = JobCancelled
+		j.cancel()
+	} else {
+		delete(m.jobs, id)
+	}
+	return j.info, true
+}
+
+func (m *JobManager) removeExpired() {
+	now := time.Now()
+	for id, j := range m.jobs {
+		if j.info.Expires != nil && now.After(*j.info.Expires) {
+			delete(m.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package boilingdata
+
+import (
+	"expvar"
+	"sync"
+)
+
+// Counters published on /debug/vars under "boilingdata"
+var metrics = expvar.NewMap("boilingdata")
+var cacheByUser = expvar.NewMap("boilingdata_cache_by_user")
+
+func countLoginSuccess() {
+	metrics.Add("login_success", 1)
+}
+
+func countLoginFailure() {
+	metrics.Add("login_failures", 1)
+}
+
+func countLoginLockout() {
+	metrics.Add("login_lockouts", 1)
+}
+
+func countRetry() {
+	metrics.Add("query_retries", 1)
+}
+
+// countCache adds the cache hits and misses of a result to the counters of the user
+func countCache(userName string, stats CacheStats) {
+	metrics.Add("cache_hits", int64(stats.Hits))
+	metrics.Add("cache_misses", int64(stats.Misses))
+	userMetrics := userMap(cacheByUser, userName)
+	userMetrics.Add("hits", int64(stats.Hits))
+	userMetrics.Add("misses", int64(stats.Misses))
+}
+
+var userMapMu sync.Mutex
+
+// userMap returns the map of counters of the user, creating it if needed
+func userMap(parent *expvar.Map, userName string) *expvar.Map {
+	userMapMu.Lock()
+	defer userMapMu.Unlock()
+	m, ok := parent.Get(userName).(*expvar.Map)
+	if !ok {
+		m = new(expvar.Map).Init()
+		parent.Set(userName, m)
+	}
+	return m
+}
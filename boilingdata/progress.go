@@ -0,0 +1,84 @@
+package boilingdata
+
+import (
+	"math"
+
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// Progress of a query, computed from the batch, split and sub batch counters of the parts of its
+// response received so far. Every batch is an equal share of the response, every split an equal share
+// of its batch and every sub batch an equal share of its split.
+type Progress struct {
+	BatchesReceived    int     `json:"batchesReceived"`
+	TotalBatches       int     `json:"totalBatches"`
+	SplitsReceived     int     `json:"splitsReceived"`
+	SubBatchesReceived int     `json:"subBatchesReceived"`
+	Percent            float64 `json:"percent"`
+	Rows               int     `json:"rows"`
+	Bytes              int64   `json:"bytes"`
+}
+
+// WithProgress calls fn whenever a part of the response arrives
+func WithProgress(fn func(Progress)) QueryOption {
+	return func(q *Query) {
+		q.onProgress = fn
+	}
+}
+
+// WithProgressChannel sends the progress to ch whenever a part of the response arrives. Updates are
+// dropped while ch is full, so a slow reader does not hold up the query.
+func WithProgressChannel(ch chan<- Progress) QueryOption {
+	return WithProgress(func(p Progress) {
+		select {
+		case ch <- p:
+		default:
+		}
+	})
+}
+
+func newProgress(parts []*models.Response) Progress {
+	type split struct{ batch, split int }
+	progress := Progress{TotalBatches: 1}
+	subBatches := make(map[split]int)
+	totalSubBatches := make(map[split]int)
+	splits := make(map[int]map[int]bool)
+	totalSplits := make(map[int]int)
+	for _, p := range parts {
+		progress.TotalBatches = max(progress.TotalBatches, p.TotalBatches)
+	}
+	for _, p := range parts {
+		key := split{p.BatchSerial, p.SplitSerial}
+		subBatches[key]++
+		totalSubBatches[key] = max(1, p.TotalSubBatches)
+		if splits[p.BatchSerial] == nil {
+			splits[p.BatchSerial] = make(map[int]bool)
+		}
+		splits[p.BatchSerial][p.SplitSerial] = true
+		totalSplits[p.BatchSerial] = max(1, p.TotalSplitSerials)
+		progress.SubBatchesReceived++
+		progress.Rows += len(p.Data)
+		progress.Bytes += p.Bytes
+		progress.Percent += 100 / float64(progress.TotalBatches*totalSplits[p.BatchSerial]*totalSubBatches[key])
+	}
+	completeSplits := make(map[int]int)
+	for key, n := range subBatches {
+		if n >= totalSubBatches[key] {
+			completeSplits[key.batch]++
+			progress.SplitsReceived++
+		}
+	}
+	for batch, n := range completeSplits {
+		if n >= totalSplits[batch] {
+			progress.BatchesReceived++
+		}
+	}
+	progress.Percent = math.Min(100, math.Round(progress.Percent*100)/100)
+	return progress
+}
+
+// completeProgress is the progress of a finished result, e.g. one from the client side cache
+func completeProgress(result *Result) Progress {
+	batches := max(1, result.Meta.Batches)
+	return Progress{BatchesReceived: batches, TotalBatches: batches, Percent: 100, Rows: len(result.Rows), Bytes: result.Meta.Bytes}
+}
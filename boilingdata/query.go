@@ -0,0 +1,174 @@
+package boilingdata
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// Query is a single SQL statement and the options it is sent with.
+// Args are bound to the placeholders of the SQL client side, see Bind.
+type Query struct {
+	SQL          string
+	Args         []interface{}
+	Tags         []models.Tag
+	ReadCache    models.CacheMode
+	RequestID    string
+	CacheControl CacheControl
+	onProgress   func(Progress)
+	stream       *partStreamer
+}
+
+type QueryOption func(*Query)
+
+// WithTags sets tags of the query, they override default tags of the same name
+func WithTags(tags ...models.Tag) QueryOption {
+	return func(q *Query) {
+		q.Tags = tags
+	}
+}
+
+// WithReadCache sets whether BoilingData may answer from its cache, models.CacheNone by default
+func WithReadCache(readCache models.CacheMode) QueryOption {
+	return func(q *Query) {
+		q.ReadCache = readCache
+	}
+}
+
+// WithRequestID overrides the generated request id
+func WithRequestID(requestID string) QueryOption {
+	return func(q *Query) {
+		q.RequestID = requestID
+	}
+}
+
+// Execute sends the query over the websocket of the instance and waits for its result or until ctx is done
+func (instance *Instance) Execute(ctx context.Context, q Query) (result *Result, err error) {
+	payload, err := q.payload()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	defer func() {
+		instance.recordHistory(start, payload, result, err)
+	}()
+	if err := instance.applyTags(&payload); err != nil {
+		return nil, err
+	}
+	if payload.ReadCache == "" {
+		payload.ReadCache = models.CacheNone
+	}
+	cacheKey := ""
+	if instance.Cache != nil && q.CacheControl != CacheBypass {
+		cacheKey = CacheKey(payload)
+		if q.CacheControl == CacheUse {
+			if cached, ok := instance.Cache.Get(cacheKey); ok {
+				hit := *cached
+				hit.RequestID = payload.RequestID
+				hit.Meta.RequestID = payload.RequestID
+				hit.Meta.ClientCacheHit = true
+				hit.Meta.DurationMs = time.Since(start).Milliseconds()
+				hit.Meta.TimeToFirstBatchMs = hit.Meta.DurationMs
+				if q.stream != nil {
+					q.stream.rows(hit.Schema, hit.Rows)
+				}
+				if q.onProgress != nil {
+					q.onProgress(completeProgress(&hit))
+				}
+				return &hit, nil
+			}
+		}
+	}
+	run := func(ctx context.Context, onProgress func(Progress)) (*Result, error) {
+		var firstPart time.Time
+		var last Progress
+		track := func(parts []*models.Response) {
+			if firstPart.IsZero() {
+				firstPart = time.Now()
+			}
+			last = newProgress(parts)
+			if q.stream != nil {
+				q.stream.parts(parts)
+			}
+			if onProgress != nil {
+				onProgress(last)
+			}
+		}
+		response, retries, err := instance.executeWithRetry(ctx, payload, track, q.stream != nil)
+		if err != nil {
+			return nil, err
+		}
+		result := newResult(response)
+		result.Meta.RequestID = result.RequestID
+		result.Meta.TimeToFirstBatchMs = firstPart.Sub(start).Milliseconds()
+		result.Meta.Rows = len(result.Rows)
+		result.Meta.Bytes = response.Bytes
+		result.Meta.Batches = last.TotalBatches
+		result.Meta.Splits = last.SplitsReceived
+		result.Meta.SubBatches = last.SubBatchesReceived
+		result.Meta.Retries = retries
+		result.Meta.Tags = payload.Tags
+		result.Meta.Cache.Mode = payload.ReadCache
+		countCache(instance.Auth.UserName(), result.Meta.Cache)
+		return result, nil
+	}
+	shared := false
+	if instance.flights != nil && q.stream == nil {
+		// Identical queries in flight at the same time share one request
+		result, shared, err = instance.flights.do(ctx, CacheKey(payload), q.onProgress, run)
+	} else {
+		result, err = run(ctx, q.onProgress)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cacheKey != "" && !shared {
+		instance.Cache.Set(cacheKey, result)
+	}
+	// The result may be shared with other callers and the cache, the copy gets the meta of this caller
+	own := *result
+	own.Meta.Deduplicated = shared
+	own.Meta.DurationMs = time.Since(start).Milliseconds()
+	return &own, nil
+}
+
+// applyTags merges the default tags of the config and of the user with the tags of the payload
+func (instance *Instance) applyTags(payload *models.Payload) error {
+	tags, err := instance.Auth.conf().tagsFor(instance.Auth.UserName(), payload.Tags)
+	if err != nil {
+		return err
+	}
+	payload.Tags = tags
+	return nil
+}
+
+func (q Query) payload() (models.Payload, error) {
+	sql := q.SQL
+	if len(q.Args) > 0 {
+		bound, err := Bind(q.SQL, q.Args...)
+		if err != nil {
+			return models.Payload{}, err
+		}
+		sql = bound
+	}
+	payload := models.Payload{
+		MessageType: "SQL_QUERY",
+		SQL:         sql,
+		RequestID:   q.RequestID,
+		ReadCache:   q.ReadCache,
+		Tags:        q.Tags,
+	}
+	if payload.RequestID == "" {
+		payload.RequestID = newRequestID()
+	}
+	return payload, nil
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,158 @@
+package boilingdata
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginLimiter counts failed logins per key (username, client ip) and locks the key out
+// for an exponentially growing duration once too many attempts failed.
+type LoginLimiter struct {
+	MaxFailures int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	mu          sync.Mutex
+	attempts    map[string]*loginAttempts
+}
+
+type loginAttempts struct {
+	failures int
+	// pending attempts allowed and not finished yet, they count as failures until they are
+	pending     int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func NewLoginLimiter(maxFailures int, baseLockout time.Duration, maxLockout time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		MaxFailures: maxFailures,
+		BaseLockout: baseLockout,
+		MaxLockout:  maxLockout,
+		attempts:    make(map[string]*loginAttempts),
+	}
+}
+
+// Allow reserves a login attempt for all keys and reports whether it may proceed, and if not, how long
+// to wait. Attempts in progress count as failures, so no more than MaxFailures attempts of a key pass
+// before the lockout. An allowed attempt must be finished with Failure, Success or Release of every key.
+func (l *LoginLimiter) Allow(keys ...string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for _, key := range keys {
+		a, ok := l.attempts[key]
+		if !ok {
+			continue
+		}
+		if now.Before(a.lockedUntil) {
+			return false, a.lockedUntil.Sub(now)
+		}
+		// Forget old failures once the key has been quiet for longer than the max lockout
+		if a.pending == 0 && now.Sub(a.lastFailure) > l.MaxLockout {
+			delete(l.attempts, key)
+			continue
+		}
+		limit := l.MaxFailures
+		if a.failures >= l.MaxFailures {
+			// The lockout is over, let one attempt through so a failure escalates the lockout and a success resets it
+			limit = a.failures + 1
+		}
+		if a.failures+a.pending >= limit {
+			// Wait for the attempts in progress to finish
+			return false, time.Second
+		}
+	}
+	for _, key := range keys {
+		l.get(key).pending++
+	}
+	return true, 0
+}
+
+// get returns the attempts of the key, creating them if needed. Once there are more than maxKeys keys,
+// keys that are not locked out and have no attempt in progress are evicted.
+func (l *LoginLimiter) get(key string) *loginAttempts {
+	a, ok := l.attempts[key]
+	if ok {
+		return a
+	}
+	if len(l.attempts) >= maxLimiterKeys {
+		l.evict()
+	}
+	a = &loginAttempts{lastFailure: time.Now()}
+	l.attempts[key] = a
+	return a
+}
+
+// maxLimiterKeys bounds the keys of a LoginLimiter, usernames are chosen by the client
+const maxLimiterKeys = 100000
+
+// evict removes quiet keys first and then, if still needed, any key that is not locked out until
+// a tenth of maxLimiterKeys is free
+func (l *LoginLimiter) evict() {
+	now := time.Now()
+	for key, a := range l.attempts {
+		if a.pending == 0 && now.After(a.lockedUntil) && now.Sub(a.lastFailure) > l.MaxLockout {
+			delete(l.attempts, key)
+		}
+	}
+	for key, a := range l.attempts {
+		if len(l.attempts) < maxLimiterKeys*9/10 {
+			return
+		}
+		if a.pending == 0 && now.After(a.lockedUntil) {
+			delete(l.attempts, key)
+		}
+	}
+}
+
+// release ends an attempt in progress of the key
+func (l *LoginLimiter) release(key string) *loginAttempts {
+	a := l.get(key)
+	if a.pending > 0 {
+		a.pending--
+	}
+	return a
+}
+
+// Release ends an allowed attempt of the key without counting it as failure, e.g. because the attempt
+// was not about the key
+func (l *LoginLimiter) Release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a := l.release(key)
+	if a.pending == 0 && a.failures == 0 {
+		delete(l.attempts, key)
+	}
+}
+
+// Failure records a failed login for the key and returns true if the key is now locked out.
+func (l *LoginLimiter) Failure(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a := l.release(key)
+	now := time.Now()
+	a.failures++
+	a.lastFailure = now
+	if a.failures < l.MaxFailures {
+		return false
+	}
+	lockout := l.BaseLockout << uint(a.failures-l.MaxFailures)
+	if lockout <= 0 || lockout > l.MaxLockout {
+		lockout = l.MaxLockout
+	}
+	a.lockedUntil = now.Add(lockout)
+	return true
+}
+
+// Success clears the failures recorded for the key, other attempts of the key still in progress stay reserved.
+func (l *LoginLimiter) Success(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a := l.release(key)
+	if a.pending == 0 {
+		delete(l.attempts, key)
+		return
+	}
+	a.failures = 0
+	a.lockedUntil = time.Time{}
+}
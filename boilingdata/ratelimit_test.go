@@ -0,0 +1,133 @@
+package boilingdata
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoginLimiterLockout(t *testing.T) {
+	l := NewLoginLimiter(2, time.Hour, 2*time.Hour)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("user"); !ok {
+			t.Fatalf("attempt %d not allowed", i+1)
+		}
+		if locked := l.Failure("user"); locked != (i == 1) {
+			t.Errorf("failure %d: locked = %v", i+1, locked)
+		}
+	}
+	ok, wait := l.Allow("user")
+	if ok || wait <= 59*time.Minute || wait > time.Hour {
+		t.Errorf("Allow after lockout = %v, %v, want false and about an hour", ok, wait)
+	}
+	if ok, _ := l.Allow("other"); !ok {
+		t.Error("other key is locked out")
+	}
+}
+
+func TestLoginLimiterLockoutExpiry(t *testing.T) {
+	l := NewLoginLimiter(2, 50*time.Millisecond, 2*time.Second)
+	for i := 0; i < 2; i++ {
+		l.Allow("user")
+		l.Failure("user")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if ok, wait := l.Allow("user"); !ok {
+		t.Fatalf("Allow after the lockout expired = false, %v", wait)
+	}
+	// Only one attempt passes until it is finished
+	if ok, _ := l.Allow("user"); ok {
+		t.Error("second attempt after the lockout allowed")
+	}
+	l.Success("user")
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("user"); !ok {
+			t.Fatalf("attempt %d after success not allowed", i+1)
+		}
+		l.Release("user")
+	}
+}
+
+func TestLoginLimiterEscalation(t *testing.T) {
+	l := NewLoginLimiter(1, 40*time.Millisecond, 140*time.Millisecond)
+	lockouts := []time.Duration{40 * time.Millisecond, 80 * time.Millisecond, 140 * time.Millisecond}
+	for i, want := range lockouts {
+		if ok, wait := l.Allow("user"); !ok {
+			t.Fatalf("attempt %d not allowed, wait %v", i+1, wait)
+		}
+		if !l.Failure("user") {
+			t.Fatalf("failure %d did not lock out", i+1)
+		}
+		_, wait := l.Allow("user")
+		if wait <= want-30*time.Millisecond || wait > want {
+			t.Errorf("lockout %d = %v, want %v", i+1, wait, want)
+		}
+		time.Sleep(wait + 5*time.Millisecond)
+	}
+}
+
+func TestLoginLimiterPending(t *testing.T) {
+	l := NewLoginLimiter(2, time.Hour, 2*time.Hour)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("user", "ip"); !ok {
+			t.Fatalf("attempt %d not allowed", i+1)
+		}
+	}
+	// Attempts in progress count as failures
+	if ok, wait := l.Allow("user"); ok || wait != time.Second {
+		t.Errorf("Allow with two attempts in progress = %v, %v, want false, 1s", ok, wait)
+	}
+	if ok, _ := l.Allow("other", "ip"); ok {
+		t.Error("attempt of a key at the limit allowed")
+	}
+	if _, ok := l.attempts["other"]; ok {
+		t.Error("denied attempt reserved a key")
+	}
+	// A failure stays counted while the other attempt is in progress
+	l.Failure("user")
+	l.Release("ip")
+	if ok, _ := l.Allow("user"); ok {
+		t.Error("attempt allowed with one failure and one attempt in progress")
+	}
+	l.Success("user")
+	l.Release("ip")
+	if len(l.attempts) != 0 {
+		t.Errorf("attempts = %v, want none", l.attempts)
+	}
+	// A success clears the failures but keeps the other attempt reserved
+	l.Allow("user")
+	l.Allow("user")
+	l.Success("user")
+	if a := l.attempts["user"]; a == nil || a.failures != 0 || a.pending != 1 {
+		t.Errorf("attempts of user after success = %+v, want one pending", a)
+	}
+	if ok, _ := l.Allow("user"); !ok {
+		t.Error("attempt after success not allowed")
+	}
+	if ok, _ := l.Allow("user"); ok {
+		t.Error("third attempt allowed while two are in progress")
+	}
+}
+
+func TestLoginLimiterEviction(t *testing.T) {
+	l := NewLoginLimiter(2, time.Hour, 2*time.Hour)
+	l.Allow("locked")
+	l.Failure("locked")
+	l.Allow("locked")
+	l.Failure("locked")
+	l.Allow("pending")
+	for i := 0; len(l.attempts) < maxLimiterKeys; i++ {
+		key := fmt.Sprint("user", i)
+		l.Allow(key)
+		l.Failure(key)
+	}
+	l.Allow("new")
+	if n := len(l.attempts); n >= maxLimiterKeys*9/10+1 {
+		t.Errorf("%d keys after eviction", n)
+	}
+	for _, key := range []string{"locked", "pending", "new"} {
+		if _, ok := l.attempts[key]; !ok {
+			t.Errorf("key %s evicted", key)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package boilingdata
+
+import (
+	"strings"
+
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// Result of a query
+type Result struct {
+	RequestID string                   `json:"requestId"`
+	Schema    *models.Schema           `json:"schema"`
+	Rows      []map[string]interface{} `json:"data"`
+	Meta      ResultMeta               `json:"meta"`
+	// response is the assembled websocket response, returned by Instance.Query
+	response *models.Response
+}
+
+// ResultMeta describes how a query was run
+type ResultMeta struct {
+	RequestID string `json:"requestId"`
+	// TimeToFirstBatchMs is the time from starting the query until the first part of the response arrived
+	TimeToFirstBatchMs int64 `json:"timeToFirstBatchMs"`
+	DurationMs         int64 `json:"durationMs"`
+	Rows               int   `json:"rows"`
+	// Bytes is the size of the messages the result was received in
+	Bytes      int64 `json:"bytes"`
+	Batches    int   `json:"batches"`
+	Splits     int   `json:"splits"`
+	SubBatches int   `json:"subBatches"`
+	// Tags the query was sent with, after merging the default tags
+	Tags  []models.Tag `json:"tags"`
+	Cache CacheStats   `json:"cache"`
+	// ClientCacheHit is set when the result came from the client side result cache
+	ClientCacheHit bool `json:"clientCacheHit"`
+	// Deduplicated is set when the result was shared with an identical query in flight at the same time
+	Deduplicated bool `json:"deduplicated"`
+	// Retries is the number of times the query was sent again after a retryable error
+	Retries int `json:"retries"`
+}
+
+// CacheStats aggregates the cache info of all parts of a response
+type CacheStats struct {
+	Mode   models.CacheMode `json:"mode"`
+	Hits   int              `json:"hits"`
+	Misses int              `json:"misses"`
+	// Info has the distinct cache info values sent by BoilingData
+	Info []string `json:"info,omitempty"`
+}
+
+func newCacheStats(cacheInfos []string) CacheStats {
+	stats := CacheStats{}
+	seen := make(map[string]bool)
+	for _, info := range cacheInfos {
+		if info == "" {
+			continue
+		}
+		if strings.Contains(strings.ToLower(info), "hit") {
+			stats.Hits++
+		} else {
+			stats.Misses++
+		}
+		if !seen[info] {
+			seen[info] = true
+			stats.Info = append(stats.Info, info)
+		}
+	}
+	return stats
+}
+
+func newResult(response *models.Response) *Result {
+	return &Result{
+		RequestID: response.RequestID,
+		Schema:    response.Schema,
+		Rows:      response.Data,
+		Meta:      ResultMeta{Cache: newCacheStats(response.CacheInfos)},
+		response:  response,
+	}
+}
+
+// Response returns the assembled websocket response of the result, nil for results read back from
+// a cursor spill file
+func (r *Result) Response() *models.Response {
+	if r.response == nil {
+		return nil
+	}
+	response := *r.response
+	response.RequestID = r.RequestID
+	return &response
+}
+
+// Columns returns the column names in the order they were sent
+func (r *Result) Columns() []string {
+	return r.Schema.Names()
+}
+
+// Page returns a copy of the result with limit rows starting at offset, all rows after offset if limit is 0
+func (r *Result) Page(offset int, limit int) *Result {
+	page := *r
+	offset = min(max(offset, 0), len(r.Rows))
+	end := len(r.Rows)
+	if limit > 0 {
+		end = min(offset+limit, end)
+	}
+	page.Rows = r.Rows[offset:end]
+	return &page
+}
@@ -0,0 +1,90 @@
+package boilingdata
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// RetryPolicy decides how often a failed query is sent again. Zero values use the defaults,
+// set MaxAttempts to 1 to disable retries.
+type RetryPolicy struct {
+	// MaxAttempts including the first one, 3 by default
+	MaxAttempts int `json:"maxAttempts"`
+	// BackoffMillis is the wait before the first retry, 200 by default. It doubles for every further
+	// retry up to MaxBackoffMillis, 5000 by default. Waits are jittered.
+	BackoffMillis    int `json:"backoffMillis"`
+	MaxBackoffMillis int `json:"maxBackoffMillis"`
+	// Retryable classifies errors, IsRetryable by default
+	Retryable func(error) bool `json:"-"`
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return 3
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// backoff returns the wait before the given retry, between half and all of the exponential backoff
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	base := time.Duration(p.BackoffMillis) * time.Millisecond
+	if base <= 0 {
+		base = 200 * time.Millisecond
+	}
+	limit := time.Duration(p.MaxBackoffMillis) * time.Millisecond
+	if limit <= 0 {
+		limit = 5 * time.Second
+	}
+	wait := base
+	for i := 1; i < retry && wait < limit; i++ {
+		wait *= 2
+	}
+	wait = min(wait, limit)
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// executeWithRetry executes the payload, sending it again with a new request id while the error is
+// retryable. It returns the response of the successful attempt and the number of retries. A streamed
+// query is not sent again once parts of it were handed to onParts.
+func (instance *Instance) executeWithRetry(ctx context.Context, payload models.Payload, onParts func([]*models.Response), streamed bool) (*models.Response, int, error) {
+	policy := instance.Auth.conf().Retry
+	received := false
+	for attempt := 1; ; attempt++ {
+		response, err := instance.execute(ctx, payload, func(parts []*models.Response) {
+			received = true
+			onParts(parts)
+		})
+		if err == nil {
+			return response, attempt - 1, nil
+		}
+		var queryErr *QueryError
+		if errors.As(err, &queryErr) {
+			queryErr.Attempts = attempt
+		}
+		if ctx.Err() != nil || attempt >= policy.attempts() || !policy.retryable(err) || (streamed && received) {
+			return nil, attempt - 1, err
+		}
+		wait := policy.backoff(attempt)
+		log.Printf("Query %s failed, retrying in %v -> %v", payload.RequestID, wait, err)
+		countRetry()
+		select {
+		case <-ctx.Done():
+			return nil, attempt - 1, ctx.Err()
+		case <-time.After(wait):
+		}
+		// Parts of the failed attempt that arrive late must not end up in the result of the new one
+		payload.RequestID = newRequestID()
+	}
+}
@@ -0,0 +1,64 @@
+package boilingdata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/pavi6691/go-boilingdata/internal/wstest"
+	"github.com/pavi6691/go-boilingdata/models"
+	"github.com/pavi6691/go-boilingdata/wsclient"
+)
+
+// newTestInstance returns an instance in IAM mode connecting to the websocket server at url
+func newTestInstance(url string, retry RetryPolicy) *Instance {
+	cfg := DefaultConfig()
+	cfg.WssURL = url
+	cfg.Retry = retry
+	auth := NewIAMAuth("test", credentials.NewStaticCredentials("AKID", "SECRET", ""))
+	auth.config = &cfg
+	return newInstance(auth)
+}
+
+func TestRetryAfterDroppedConnection(t *testing.T) {
+	url := wstest.NewServer(t, func(conn int, payload models.Payload) interface{} {
+		if conn == 1 {
+			// Drop the connection in the middle of the first query
+			return nil
+		}
+		return wstest.Data(payload, []map[string]interface{}{{"a": 1}})
+	})
+
+	var failures []error
+	instance := newTestInstance(url, RetryPolicy{
+		MaxAttempts:   3,
+		BackoffMillis: 1,
+		Retryable: func(err error) bool {
+			failures = append(failures, err)
+			return IsRetryable(err)
+		},
+	})
+	defer instance.Wsc.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := instance.Execute(ctx, Query{SQL: "SELECT 1"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if result.Meta.Retries != 1 {
+		t.Errorf("retries = %d, want 1", result.Meta.Retries)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("got %d failed attempts, want 1", len(failures))
+	}
+	var transportErr *wsclient.TransportError
+	if !errors.As(failures[0], &transportErr) {
+		t.Errorf("failed attempt error %v is not a *wsclient.TransportError", failures[0])
+	}
+	if !errors.Is(failures[0], ErrTransport) {
+		t.Errorf("failed attempt error %v is not ErrTransport", failures[0])
+	}
+}
@@ -0,0 +1,381 @@
+package boilingdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+const (
+	// VisibilityPrivate saved queries are only visible to their owner
+	VisibilityPrivate = "private"
+	// VisibilityShared saved queries are visible to all users, only the owner can change them
+	VisibilityShared = "shared"
+)
+
+// Types of saved query parameters, an empty type accepts any value
+const (
+	ParamString    = "string"
+	ParamNumber    = "number"
+	ParamBoolean   = "boolean"
+	ParamTimestamp = "timestamp"
+	ParamList      = "list"
+)
+
+var savedQueryName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// SavedQueryParam is a parameter of a saved query. The SQL refers to the parameters as $1, $2.. in the
+// order they are declared.
+type SavedQueryParam struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+	// Default is used when the parameter is not given, parameters without default are required
+	Default interface{} `json:"default,omitempty"`
+}
+
+// SavedQuery is a named, parameterized query. Names are per owner, saving a query with a name the owner
+// already used adds a new version.
+type SavedQuery struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	SQL         string            `json:"sql"`
+	Params      []SavedQueryParam `json:"params,omitempty"`
+	Tags        []models.Tag      `json:"tags,omitempty"`
+	ReadCache   models.CacheMode  `json:"readCache,omitempty"`
+	Owner       string            `json:"owner"`
+	Visibility  string            `json:"visibility"`
+	Version     int               `json:"version"`
+	Created     time.Time         `json:"created"`
+}
+
+func (q SavedQuery) Validate() error {
+	if !savedQueryName.MatchString(q.Name) {
+		return fmt.Errorf("name must be letters, digits, '_', '.' or '-'")
+	}
+	if q.SQL == "" {
+		return fmt.Errorf("sql is required")
+	}
+	if q.Visibility != VisibilityPrivate && q.Visibility != VisibilityShared {
+		return fmt.Errorf("visibility must be %s or %s", VisibilityPrivate, VisibilityShared)
+	}
+	if q.ReadCache != "" {
+		if err := q.ReadCache.Validate(); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool)
+	for _, p := range q.Params {
+		if p.Name == "" || seen[p.Name] {
+			return fmt.Errorf("parameter names must be unique and not empty")
+		}
+		seen[p.Name] = true
+		switch p.Type {
+		case "", ParamString, ParamNumber, ParamBoolean, ParamTimestamp, ParamList:
+		default:
+			return fmt.Errorf("parameter %s: unknown type %q", p.Name, p.Type)
+		}
+		if p.Default != nil {
+			if _, err := p.value(p.Default); err != nil {
+				return fmt.Errorf("parameter %s: default %v", p.Name, err)
+			}
+		}
+	}
+	// Every parameter must be used by a placeholder and every placeholder needs a parameter
+	if _, err := Bind(q.SQL, make([]interface{}, len(q.Params))...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// VisibleTo reports whether the user may see and run the query
+func (q SavedQuery) VisibleTo(user string) bool {
+	return q.Owner == user || q.Visibility == VisibilityShared
+}
+
+// Query binds the parameters, decoded from JSON with numbers as json.Number, to a query
+func (q SavedQuery) Query(params map[string]interface{}) (Query, error) {
+	args := make([]interface{}, len(q.Params))
+	known := make(map[string]bool, len(q.Params))
+	for i, p := range q.Params {
+		known[p.Name] = true
+		value, ok := params[p.Name]
+		if !ok {
+			if p.Default == nil {
+				return Query{}, fmt.Errorf("parameter %s is required", p.Name)
+			}
+			value = p.Default
+		}
+		arg, err := p.value(value)
+		if err != nil {
+			return Query{}, fmt.Errorf("parameter %s: %v", p.Name, err)
+		}
+		args[i] = arg
+	}
+	for name := range params {
+		if !known[name] {
+			return Query{}, fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+	return Query{SQL: q.SQL, Args: args, Tags: q.Tags, ReadCache: q.ReadCache}, nil
+}
+
+// value checks a JSON value against the type of the parameter and converts timestamps
+func (p SavedQueryParam) value(value interface{}) (interface{}, error) {
+	ok := true
+	switch p.Type {
+	case ParamString:
+		_, ok = value.(string)
+	case ParamNumber:
+		switch value.(type) {
+		case json.Number, float64:
+		default:
+			ok = false
+		}
+	case ParamBoolean:
+		_, ok = value.(bool)
+	case ParamList:
+		_, ok = value.([]interface{})
+	case ParamTimestamp:
+		s, isString := value.(string)
+		if !isString {
+			return nil, fmt.Errorf("expected an RFC3339 timestamp, got %T", value)
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("expected an RFC3339 timestamp: %v", err)
+		}
+		return t, nil
+	}
+	if _, isObject := value.(map[string]interface{}); isObject {
+		return nil, fmt.Errorf("objects are not supported")
+	}
+	if !ok {
+		return nil, fmt.Errorf("expected a %s, got %T", p.Type, value)
+	}
+	return value, nil
+}
+
+// ErrAmbiguousSavedQuery is returned by ResolveSavedQuery when several users shared a query of the name
+var ErrAmbiguousSavedQuery = errors.New("several users shared a saved query of this name, set its owner")
+
+// SavedQueryStore keeps all versions of saved queries by owner and name, it must be safe for concurrent use
+type SavedQueryStore interface {
+	// Save adds the query as the next version of its owner and name and returns it with version and creation time set
+	Save(q SavedQuery) (SavedQuery, error)
+	// Get returns a version of the query, the latest one for version 0
+	Get(owner string, name string, version int) (SavedQuery, bool, error)
+	// Versions returns all versions of the query, oldest first
+	Versions(owner string, name string) ([]SavedQuery, error)
+	// List returns the latest version of every query
+	List() ([]SavedQuery, error)
+	// Delete removes all versions of the query
+	Delete(owner string, name string) error
+}
+
+// ResolveSavedQuery returns a version of the query of the name visible to the user, the latest one for version 0.
+// Without owner the user's own query is returned and otherwise a query another user shared. Queries the user
+// can not see are not found.
+func ResolveSavedQuery(store SavedQueryStore, user string, owner string, name string, version int) (SavedQuery, bool, error) {
+	if owner == "" {
+		own, err := store.Versions(user, name)
+		if err != nil {
+			return SavedQuery{}, false, err
+		}
+		if len(own) > 0 {
+			return store.Get(user, name, version)
+		}
+		queries, err := store.List()
+		if err != nil {
+			return SavedQuery{}, false, err
+		}
+		for _, q := range queries {
+			if q.Name != name || q.Owner == user || !q.VisibleTo(user) {
+				continue
+			}
+			if owner != "" {
+				return SavedQuery{}, false, ErrAmbiguousSavedQuery
+			}
+			owner = q.Owner
+		}
+		if owner == "" {
+			return SavedQuery{}, false, nil
+		}
+	}
+	q, found, err := store.Get(owner, name, version)
+	if err != nil || !found || !q.VisibleTo(user) {
+		return SavedQuery{}, false, err
+	}
+	return q, true, nil
+}
+
+var savedQueriesMu sync.RWMutex
+var savedQueryStore SavedQueryStore
+
+// SetSavedQueryStore sets where saved queries are kept, nil disables them
+func SetSavedQueryStore(store SavedQueryStore) {
+	savedQueriesMu.Lock()
+	defer savedQueriesMu.Unlock()
+	savedQueryStore = store
+}
+
+// SavedQueries returns the saved query store, nil when saved queries are disabled
+func SavedQueries() SavedQueryStore {
+	savedQueriesMu.RLock()
+	defer savedQueriesMu.RUnlock()
+	return savedQueryStore
+}
+
+// FileSavedQueryStore keeps saved queries in a JSON file
+type FileSavedQueryStore struct {
+	filename string
+	mu       sync.Mutex
+}
+
+func NewFileSavedQueryStore(filename string) (*FileSavedQueryStore, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return nil, err
+	}
+	store := &FileSavedQueryStore{filename: filename}
+	if _, err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// DefaultSavedQueriesFile returns ~/.boilingdata/saved-queries.json
+func DefaultSavedQueriesFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "saved-queries.json"
+	}
+	return filepath.Join(home, ".boilingdata", "saved-queries.json")
+}
+
+func (s *FileSavedQueryStore) Save(q SavedQuery) (SavedQuery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queries, err := s.load()
+	if err != nil {
+		return SavedQuery{}, err
+	}
+	key := savedQueryKey(q.Owner, q.Name)
+	versions := queries[key]
+	q.Version = 1
+	if len(versions) > 0 {
+		q.Version = versions[len(versions)-1].Version + 1
+	}
+	q.Created = time.Now().UTC()
+	queries[key] = append(versions, q)
+	if err := s.write(queries); err != nil {
+		return SavedQuery{}, err
+	}
+	return q, nil
+}
+
+func (s *FileSavedQueryStore) Get(owner string, name string, version int) (SavedQuery, bool, error) {
+	versions, err := s.Versions(owner, name)
+	if err != nil || len(versions) == 0 {
+		return SavedQuery{}, false, err
+	}
+	if version == 0 {
+		return versions[len(versions)-1], true, nil
+	}
+	for _, q := range versions {
+		if q.Version == version {
+			return q, true, nil
+		}
+	}
+	return SavedQuery{}, false, nil
+}
+
+func (s *FileSavedQueryStore) Versions(owner string, name string) ([]SavedQuery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return queries[savedQueryKey(owner, name)], nil
+}
+
+func (s *FileSavedQueryStore) List() ([]SavedQuery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	latest := make([]SavedQuery, 0, len(queries))
+	for _, versions := range queries {
+		if len(versions) > 0 {
+			latest = append(latest, versions[len(versions)-1])
+		}
+	}
+	sort.Slice(latest, func(i, j int) bool {
+		if latest[i].Name != latest[j].Name {
+			return latest[i].Name < latest[j].Name
+		}
+		return latest[i].Owner < latest[j].Owner
+	})
+	return latest, nil
+}
+
+func (s *FileSavedQueryStore) Delete(owner string, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queries, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(queries, savedQueryKey(owner, name))
+	return s.write(queries)
+}
+
+// savedQueryKey is the key of the versions of a query in the file, names can't contain '/'
+func savedQueryKey(owner string, name string) string {
+	return owner + "/" + name
+}
+
+func (s *FileSavedQueryStore) load() (map[string][]SavedQuery, error) {
+	queries := make(map[string][]SavedQuery)
+	data, err := os.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		return queries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalNumbers(data, &queries); err != nil {
+		return nil, fmt.Errorf("failed to parse saved queries %s: %v", s.filename, err)
+	}
+	return queries, nil
+}
+
+// write replaces the file through a temporary file, so a failed write keeps the old queries
+func (s *FileSavedQueryStore) write(queries map[string][]SavedQuery) error {
+	data, err := json.MarshalIndent(queries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.filename)
+}
+
+// unmarshalNumbers is json.Unmarshal keeping numbers as json.Number
+func unmarshalNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,60 @@
+package boilingdata
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestSavedQueryNamesPerOwner(t *testing.T) {
+	store, err := NewFileSavedQueryStore(filepath.Join(t.TempDir(), "saved-queries.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := func(owner, name, visibility string) SavedQuery {
+		q, err := store.Save(SavedQuery{Name: name, SQL: "SELECT 1", Owner: owner, Visibility: visibility})
+		if err != nil {
+			t.Fatalf("Save(%s, %s): %v", owner, name, err)
+		}
+		return q
+	}
+	save("alice", "private", VisibilityPrivate)
+	if q := save("bob", "private", VisibilityPrivate); q.Version != 1 {
+		t.Errorf("version of bob's query = %d, want 1", q.Version)
+	}
+	save("alice", "shared", VisibilityShared)
+	save("alice", "both", VisibilityShared)
+	save("bob", "both", VisibilityShared)
+
+	tests := []struct {
+		user, owner, name string
+		wantOwner         string
+		wantErr           error
+	}{
+		{"bob", "", "private", "bob", nil},
+		{"carol", "", "private", "", nil},
+		{"carol", "alice", "private", "", nil},
+		{"bob", "", "shared", "alice", nil},
+		{"bob", "alice", "shared", "alice", nil},
+		{"bob", "", "both", "bob", nil},
+		{"carol", "", "both", "", ErrAmbiguousSavedQuery},
+		{"carol", "bob", "both", "bob", nil},
+	}
+	for _, test := range tests {
+		q, found, err := ResolveSavedQuery(store, test.user, test.owner, test.name, 0)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("%s resolving %s/%s: error %v, want %v", test.user, test.owner, test.name, err, test.wantErr)
+			continue
+		}
+		if found != (test.wantOwner != "") || q.Owner != test.wantOwner {
+			t.Errorf("%s resolving %s/%s = %s, %v, want %s", test.user, test.owner, test.name, q.Owner, found, test.wantOwner)
+		}
+	}
+
+	if err := store.Delete("bob", "private"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := store.Get("alice", "private", 0); !found {
+		t.Error("deleting bob's query removed alice's query")
+	}
+}
@@ -0,0 +1,396 @@
+package boilingdata
+
+import (
+	"database/sql"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rows iterates over the rows of a result
+//
+//	rows := result.Iter()
+//	for rows.Next() {
+//		var t T
+//		if err := rows.Scan(&t); err != nil {
+//			return err
+//		}
+//	}
+type Rows struct {
+	result *Result
+	index  int
+}
+
+func (r *Result) Iter() *Rows {
+	return &Rows{result: r, index: -1}
+}
+
+func (r *Rows) Next() bool {
+	if r.index+1 >= len(r.result.Rows) {
+		return false
+	}
+	r.index++
+	return true
+}
+
+// Scan copies the current row into the struct pointed to by dest
+func (r *Rows) Scan(dest interface{}) error {
+	if r.index < 0 || r.index >= len(r.result.Rows) {
+		return fmt.Errorf("boilingdata: Scan called without calling Next")
+	}
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("boilingdata: Scan destination must be a pointer to a struct, got %T", dest)
+	}
+	return scanRow(r.result.Rows[r.index], v.Elem())
+}
+
+// ScanAll copies all rows into the slice pointed to by dest, a *[]T or *[]*T where T is a struct.
+// Columns are matched to fields by their `bd:"column"` tag or else case insensitively by field name,
+// fields tagged `bd:"-"` are skipped. Use pointer fields for columns that can be NULL.
+func (r *Result) ScanAll(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("boilingdata: ScanAll destination must be a pointer to a slice, got %T", dest)
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	structType := elemType
+	if isPtr {
+		structType = elemType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("boilingdata: ScanAll destination must be a slice of structs, got %T", dest)
+	}
+	out := reflect.MakeSlice(slice.Type(), 0, len(r.Rows))
+	for i, row := range r.Rows {
+		item := reflect.New(structType)
+		if err := scanRow(row, item.Elem()); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+		if isPtr {
+			out = reflect.Append(out, item)
+		} else {
+			out = reflect.Append(out, item.Elem())
+		}
+	}
+	slice.Set(out)
+	return nil
+}
+
+var fieldCache sync.Map
+
+// structFields maps lower cased column names to field index paths, flattening embedded structs
+func structFields(t reflect.Type) map[string][]int {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+	fields := make(map[string][]int)
+	collectFields(t, nil, fields)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, parent []int, fields map[string][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("bd")
+		if tag == "-" {
+			continue
+		}
+		index := append(append([]int{}, parent...), i)
+		if f.Anonymous && tag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				collectFields(ft, index, fields)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = f.Name
+		}
+		name = strings.ToLower(name)
+		// Outer fields win over fields of embedded structs
+		if existing, ok := fields[name]; ok && len(existing) <= len(index) {
+			continue
+		}
+		fields[name] = index
+	}
+}
+
+func scanRow(row map[string]interface{}, dest reflect.Value) error {
+	fields := structFields(dest.Type())
+	for column, value := range row {
+		index, ok := fields[strings.ToLower(column)]
+		if !ok {
+			continue
+		}
+		field, err := fieldByIndex(dest, index)
+		if err != nil {
+			return err
+		}
+		if err := setValue(field, value); err != nil {
+			return fmt.Errorf("boilingdata: column %q into field %s: %w", column, dest.Type().FieldByIndex(index).Name, err)
+		}
+	}
+	return nil
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex allocating nil embedded struct pointers on the way
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("boilingdata: cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var bigFloatType = reflect.TypeOf(big.Float{})
+var bigRatType = reflect.TypeOf(big.Rat{})
+var bigIntType = reflect.TypeOf(big.Int{})
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func setValue(field reflect.Value, value interface{}) error {
+	if value == nil {
+		switch field.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+			return scanner.Scan(nil)
+		}
+		return fmt.Errorf("value is NULL, use a pointer field of type *%s", field.Type())
+	}
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := setValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+	if field.Kind() == reflect.Interface {
+		field.Set(reflect.ValueOf(value))
+		return nil
+	}
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(scannerValue(value))
+	}
+
+	switch field.Type() {
+	case timeType:
+		t, err := toTime(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case bigFloatType, bigRatType, bigIntType:
+		text, ok := numberText(value)
+		if !ok {
+			return mismatch(value, field.Type())
+		}
+		if f, ok := field.Addr().Interface().(*big.Float); ok && f.Prec() == 0 {
+			// Default precision of 64 bits would round long decimals
+			f.SetPrec(256)
+		}
+		if err := field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return fmt.Errorf("cannot parse %q as %s: %v", text, field.Type(), err)
+		}
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			field.SetString(v)
+		case json.Number:
+			field.SetString(v.String())
+		case float64:
+			field.SetString(strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			field.SetString(strconv.FormatBool(v))
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			field.SetString(string(b))
+		}
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			field.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return mismatch(value, field.Type())
+			}
+			field.SetBool(b)
+		default:
+			return mismatch(value, field.Type())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, field.Type())
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := toUint64(value)
+		if err != nil {
+			return err
+		}
+		if field.OverflowUint(u) {
+			return fmt.Errorf("value %d overflows %s", u, field.Type())
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		text, ok := numberText(value)
+		if !ok {
+			return mismatch(value, field.Type())
+		}
+		f, err := strconv.ParseFloat(text, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse %q as %s", text, field.Type())
+		}
+		field.SetFloat(f)
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		// Nested values are converted through JSON
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, field.Addr().Interface()); err != nil {
+			return fmt.Errorf("cannot convert %s into %s: %v", b, field.Type(), err)
+		}
+	default:
+		return mismatch(value, field.Type())
+	}
+	return nil
+}
+
+func mismatch(value interface{}, t reflect.Type) error {
+	return fmt.Errorf("cannot convert %v (%T) into %s", value, value, t)
+}
+
+// scannerValue converts a value to one of the types a sql.Scanner expects
+func scannerValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		return v.String()
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return b
+	}
+	return value
+}
+
+// numberText returns the textual form of a numeric value, keeping the full precision of json.Number
+func numberText(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.String(), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case string:
+		return strings.TrimSpace(v), true
+	}
+	return "", false
+}
+
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, fmt.Errorf("value %v is not an integer in range", v)
+		}
+		return int64(v), nil
+	case json.Number, string:
+		text, _ := numberText(v)
+		i, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse %q as integer", text)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("cannot convert %v (%T) into integer", value, value)
+}
+
+func toUint64(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) || v < 0 || v >= math.MaxUint64 {
+			return 0, fmt.Errorf("value %v is not an unsigned integer in range", v)
+		}
+		return uint64(v), nil
+	case json.Number, string:
+		text, _ := numberText(v)
+		u, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse %q as unsigned integer", text)
+		}
+		return u, nil
+	}
+	return 0, fmt.Errorf("cannot convert %v (%T) into unsigned integer", value, value)
+}
+
+// toTime parses timestamps sent as strings, numbers are taken as milliseconds since epoch
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as time", v)
+	case float64, json.Number:
+		ms, err := toInt64(v)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot convert %v (%T) into time.Time", value, value)
+}
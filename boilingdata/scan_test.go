@@ -0,0 +1,242 @@
+package boilingdata
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetValue(t *testing.T) {
+	seven := 7
+	longDecimal, _ := new(big.Float).SetPrec(256).SetString("0.12345678901234567890123456789")
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	tests := []struct {
+		name  string
+		value interface{}
+		// want is the expected field value, its type is the type of the field
+		want interface{}
+	}{
+		{"string", "a", "a"},
+		{"decimal into string", json.Number("1.50"), "1.50"},
+		{"float into string", 0.5, "0.5"},
+		{"bool into string", true, "true"},
+		{"object into string", map[string]interface{}{"a": json.Number("1")}, `{"a":1}`},
+		{"bool", true, true},
+		{"bool from string", "true", true},
+		{"int", json.Number("42"), 42},
+		{"int8 from float", float64(-3), int8(-3)},
+		{"int64 from string", "9223372036854775807", int64(math.MaxInt64)},
+		{"uint64", json.Number("18446744073709551615"), uint64(math.MaxUint64)},
+		{"float32", json.Number("1.5"), float32(1.5)},
+		{"float64", 2.25, 2.25},
+		{"float64 from string", " 2.5 ", 2.5},
+		{"big.Int", json.Number("123456789012345678901234567890"), bigInt},
+		{"big.Float keeps precision", json.Number("0.12345678901234567890123456789"), longDecimal},
+		{"big.Rat", json.Number("1.25"), big.NewRat(5, 4)},
+		{"big.Rat from string", "-0.5", big.NewRat(-1, 2)},
+		{"RFC3339", "2024-01-02T03:04:05.5Z", time.Date(2024, 1, 2, 3, 4, 5, 5e8, time.UTC)},
+		{"timestamp with zone", "2024-01-02 03:04:05+02:00", time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC)},
+		{"timestamp with hour zone", "2024-01-02 03:04:05.123-05", time.Date(2024, 1, 2, 8, 4, 5, 123e6, time.UTC)},
+		{"timestamp without zone", "2024-01-02 03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"T timestamp without zone", "2024-01-02T03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"date", "2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"epoch milliseconds", json.Number("1704164645000"), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"pointer", json.Number("7"), &seven},
+		{"NULL into pointer", nil, (*int)(nil)},
+		{"NULL into slice", nil, []int(nil)},
+		{"NULL into map", nil, map[string]int(nil)},
+		{"list", []interface{}{json.Number("1"), json.Number("2")}, []int{1, 2}},
+		{"map", map[string]interface{}{"a": json.Number("1")}, map[string]int{"a": 1}},
+		{"struct", map[string]interface{}{"a": "x"}, struct {
+			A string `json:"a"`
+		}{"x"}},
+		{"sql.Scanner", "a", sql.NullString{String: "a", Valid: true}},
+		{"sql.Scanner with number", json.Number("5"), sql.NullInt64{Int64: 5, Valid: true}},
+		{"sql.Scanner with decimal", json.Number("1.5"), sql.NullFloat64{Float64: 1.5, Valid: true}},
+		{"NULL into sql.Scanner", nil, sql.NullString{}},
+	}
+	for _, test := range tests {
+		field := reflect.New(reflect.TypeOf(test.want)).Elem()
+		if err := setValue(field, test.value); err != nil {
+			t.Errorf("%s: setValue(%v) failed: %v", test.name, test.value, err)
+			continue
+		}
+		if got := field.Interface(); !scannedEqual(got, test.want) {
+			t.Errorf("%s: setValue(%v) = %v, want %v", test.name, test.value, got, test.want)
+		}
+	}
+}
+
+// scannedEqual compares times and big numbers by value
+func scannedEqual(got, want interface{}) bool {
+	switch w := want.(type) {
+	case time.Time:
+		return w.Equal(got.(time.Time))
+	case *big.Int:
+		return w.Cmp(got.(*big.Int)) == 0
+	case *big.Float:
+		return w.Cmp(got.(*big.Float)) == 0
+	case *big.Rat:
+		return w.Cmp(got.(*big.Rat)) == 0
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func TestSetValueErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		field interface{}
+	}{
+		{"NULL into int", nil, 0},
+		{"NULL into string", nil, ""},
+		{"text into int", "abc", 0},
+		{"fraction into int", 1.5, 0},
+		{"decimal into int", json.Number("1.5"), 0},
+		{"overflow", json.Number("300"), int8(0)},
+		{"negative into uint", json.Number("-1"), uint(0)},
+		{"negative float into uint", -1.0, uint(0)},
+		{"number into bool", json.Number("1"), false},
+		{"text into bool", "yes", false},
+		{"bool into float", true, 0.0},
+		{"text into float", "abc", 0.0},
+		{"bool into time", true, time.Time{}},
+		{"text into time", "yesterday", time.Time{}},
+		{"bool into big.Int", true, big.Int{}},
+		{"text into big.Int", "abc", big.Int{}},
+		{"object into list", map[string]interface{}{"a": 1}, []int{}},
+		{"text into complex", "1", complex128(0)},
+	}
+	for _, test := range tests {
+		field := reflect.New(reflect.TypeOf(test.field)).Elem()
+		if err := setValue(field, test.value); err == nil {
+			t.Errorf("%s: setValue(%v) into %s = %v, want an error", test.name, test.value, field.Type(), field.Interface())
+		}
+	}
+}
+
+type scanBase struct {
+	ID      int
+	Created time.Time
+}
+
+// ScanExtra is exported, so an embedded pointer to it can be allocated
+type ScanExtra struct {
+	Note string `bd:"note"`
+}
+
+type scanExtra struct {
+	Note string `bd:"note"`
+}
+
+type scanTarget struct {
+	scanBase
+	*ScanExtra
+	Name     string   `bd:"full_name"`
+	Amount   *big.Rat `bd:"amount"`
+	Price    *float64
+	Skipped  string `bd:"-"`
+	Tags     []string
+	Raw      interface{}
+	ignored  string
+	Created2 time.Time `bd:"created"`
+}
+
+func TestScanAll(t *testing.T) {
+	result := &Result{Rows: []map[string]interface{}{
+		{
+			"id": json.Number("1"), "FULL_NAME": "a", "amount": json.Number("10.50"), "price": json.Number("2.5"),
+			"skipped": "x", "tags": []interface{}{"t1"}, "raw": json.Number("3"), "ignored": "x",
+			"created": "2024-01-02", "note": "n", "unknown": "x",
+		},
+		{"id": json.Number("2"), "full_name": "b", "amount": nil, "price": nil, "tags": nil, "raw": nil},
+	}}
+	var rows []scanTarget
+	if err := result.ScanAll(&rows); err != nil {
+		t.Fatalf("ScanAll: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	first := rows[0]
+	if first.ID != 1 || first.Name != "a" || first.Amount.Cmp(big.NewRat(21, 2)) != 0 || *first.Price != 2.5 {
+		t.Errorf("first row = %+v", first)
+	}
+	if first.Skipped != "" || first.ignored != "" {
+		t.Errorf("skipped and unexported fields were set: %+v", first)
+	}
+	if !reflect.DeepEqual(first.Tags, []string{"t1"}) || first.Raw != json.Number("3") {
+		t.Errorf("tags %v, raw %v", first.Tags, first.Raw)
+	}
+	// The tagged outer field wins over the field of the embedded struct
+	if !first.Created2.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) || !first.Created.IsZero() {
+		t.Errorf("created = %v, embedded created = %v", first.Created2, first.Created)
+	}
+	// Embedded struct pointers are allocated for their columns
+	if first.ScanExtra == nil || first.Note != "n" {
+		t.Errorf("embedded pointer = %+v", first.ScanExtra)
+	}
+	second := rows[1]
+	if second.Amount != nil || second.Price != nil || second.Tags != nil || second.Raw != nil {
+		t.Errorf("NULL values were set: %+v", second)
+	}
+	if second.ScanExtra != nil {
+		t.Error("embedded pointer allocated without its columns")
+	}
+
+	var pointers []*scanBase
+	if err := result.ScanAll(&pointers); err != nil {
+		t.Fatalf("ScanAll into pointers: %v", err)
+	}
+	if len(pointers) != 2 || pointers[1].ID != 2 {
+		t.Errorf("ScanAll into pointers = %v", pointers)
+	}
+}
+
+func TestScanAllErrors(t *testing.T) {
+	result := &Result{Rows: []map[string]interface{}{
+		{"id": json.Number("1")},
+		{"id": "abc"},
+	}}
+	var rows []scanBase
+	err := result.ScanAll(&rows)
+	if err == nil || !strings.Contains(err.Error(), "row 1") || !strings.Contains(err.Error(), `column "id" into field ID`) {
+		t.Errorf("ScanAll = %v, want an error of row 1 and column id", err)
+	}
+	var unexported []struct{ *scanExtra }
+	if err := (&Result{Rows: []map[string]interface{}{{"note": "n"}}}).ScanAll(&unexported); err == nil {
+		t.Error("ScanAll allocated an embedded pointer to an unexported struct")
+	}
+	for _, dest := range []interface{}{rows, &scanBase{}, &[]int{}, (*[]scanBase)(nil)} {
+		if err := result.ScanAll(dest); err == nil {
+			t.Errorf("ScanAll(%T) succeeded", dest)
+		}
+	}
+}
+
+func TestRowsScan(t *testing.T) {
+	result := &Result{Rows: []map[string]interface{}{{"id": json.Number("1")}, {"id": json.Number("2")}}}
+	rows := result.Iter()
+	var row scanBase
+	if err := rows.Scan(&row); err == nil {
+		t.Error("Scan before Next succeeded")
+	}
+	var ids []int
+	for rows.Next() {
+		if err := rows.Scan(&row); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, row.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("ids = %v", ids)
+	}
+	if err := rows.Scan(row); err == nil {
+		t.Error("Scan into a struct value succeeded")
+	}
+}
@@ -0,0 +1,275 @@
+package boilingdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxScheduleRuns is the number of runs kept per schedule
+const maxScheduleRuns = 100
+
+// Schedule runs a saved query on a cron expression and writes its result to a file
+type Schedule struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Cron string `json:"cron"`
+	// SavedQuery is the name of the saved query, its latest version is run unless Version is set.
+	// SavedQueryOwner selects a query another user shared, by default it's the owner's own query.
+	SavedQuery      string                 `json:"savedQuery"`
+	SavedQueryOwner string                 `json:"savedQueryOwner,omitempty"`
+	Version         int                    `json:"version,omitempty"`
+	Params          map[string]interface{} `json:"params,omitempty"`
+	Credential      CredentialRef          `json:"credential"`
+	// Format of the result files, json, ndjson or csv
+	Format    string            `json:"format"`
+	Retention SnapshotRetention `json:"retention"`
+	// TimeoutSeconds limits a run, 600 by default
+	TimeoutSeconds int       `json:"timeoutSeconds,omitempty"`
+	Enabled        bool      `json:"enabled"`
+	Owner          string    `json:"owner"`
+	Created        time.Time `json:"created"`
+	Updated        time.Time `json:"updated"`
+}
+
+// CredentialRef points to stored credentials a schedule logs in with, a profile of the credentials
+// file (source "file") or of the encrypted secrets file (source "secrets")
+type CredentialRef struct {
+	Source  string `json:"source"`
+	Profile string `json:"profile,omitempty"`
+}
+
+func (c CredentialRef) Provider() (CredentialProvider, error) {
+	switch c.Source {
+	case "file":
+		return &FileProvider{Profile: c.Profile}, nil
+	case "secrets":
+		return &EncryptedFileProvider{Profile: c.Profile}, nil
+	}
+	return nil, fmt.Errorf("credential source must be file or secrets")
+}
+
+// SnapshotRetention limits the result files kept per schedule, zero values keep everything
+type SnapshotRetention struct {
+	MaxFiles    int `json:"maxFiles,omitempty"`
+	MaxAgeHours int `json:"maxAgeHours,omitempty"`
+}
+
+func (s Schedule) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := ParseCron(s.Cron); err != nil {
+		return err
+	}
+	if s.SavedQuery == "" {
+		return fmt.Errorf("savedQuery is required")
+	}
+	if _, err := s.Credential.Provider(); err != nil {
+		return err
+	}
+	if err := ValidFormat(s.Format); err != nil {
+		return err
+	}
+	if s.Retention.MaxFiles < 0 || s.Retention.MaxAgeHours < 0 || s.TimeoutSeconds < 0 {
+		return fmt.Errorf("retention and timeout can not be negative")
+	}
+	return nil
+}
+
+func (s Schedule) timeout() time.Duration {
+	if s.TimeoutSeconds <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(s.TimeoutSeconds) * time.Second
+}
+
+// NextRun returns when the schedule runs next, the zero time when it is disabled
+func (s Schedule) NextRun(after time.Time) time.Time {
+	cron, err := ParseCron(s.Cron)
+	if err != nil || !s.Enabled {
+		return time.Time{}
+	}
+	return cron.Next(after)
+}
+
+const (
+	TriggerCron   = "cron"
+	TriggerManual = "manual"
+)
+
+// ScheduleRun records a run of a schedule
+type ScheduleRun struct {
+	ID         string    `json:"id"`
+	ScheduleID string    `json:"scheduleId"`
+	Trigger    string    `json:"trigger"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Status     string    `json:"status"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Rows       int       `json:"rows"`
+	File       string    `json:"file,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// ScheduleStore keeps schedules and their runs, it must be safe for concurrent use
+type ScheduleStore interface {
+	List() ([]Schedule, error)
+	Get(id string) (Schedule, bool, error)
+	// Save adds or replaces the schedule, a new schedule without id gets a generated one
+	Save(s Schedule) (Schedule, error)
+	// Delete removes the schedule and its runs
+	Delete(id string) error
+	AddRun(run ScheduleRun) error
+	// Runs returns the runs of the schedule, newest first
+	Runs(scheduleID string) ([]ScheduleRun, error)
+}
+
+// FileScheduleStore keeps schedules and the last runs of each in a JSON file
+type FileScheduleStore struct {
+	filename string
+	mu       sync.Mutex
+}
+
+type scheduleFile struct {
+	Schedules map[string]Schedule      `json:"schedules"`
+	Runs      map[string][]ScheduleRun `json:"runs"`
+}
+
+func NewFileScheduleStore(filename string) (*FileScheduleStore, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return nil, err
+	}
+	store := &FileScheduleStore{filename: filename}
+	if _, err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// DefaultSchedulesFile returns ~/.boilingdata/schedules.json
+func DefaultSchedulesFile() string {
+	return defaultConfigPath("schedules.json")
+}
+
+func (s *FileScheduleStore) List() ([]Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	schedules := make([]Schedule, 0, len(data.Schedules))
+	for _, schedule := range data.Schedules {
+		schedules = append(schedules, schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].Created.Before(schedules[j].Created)
+	})
+	return schedules, nil
+}
+
+func (s *FileScheduleStore) Get(id string) (Schedule, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return Schedule{}, false, err
+	}
+	schedule, ok := data.Schedules[id]
+	return schedule, ok, nil
+}
+
+func (s *FileScheduleStore) Save(schedule Schedule) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return Schedule{}, err
+	}
+	if schedule.ID == "" {
+		schedule.ID = newRequestID()
+	}
+	data.Schedules[schedule.ID] = schedule
+	if err := s.write(data); err != nil {
+		return Schedule{}, err
+	}
+	return schedule, nil
+}
+
+func (s *FileScheduleStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(data.Schedules, id)
+	delete(data.Runs, id)
+	return s.write(data)
+}
+
+func (s *FileScheduleStore) AddRun(run ScheduleRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	runs := append([]ScheduleRun{run}, data.Runs[run.ScheduleID]...)
+	if len(runs) > maxScheduleRuns {
+		runs = runs[:maxScheduleRuns]
+	}
+	data.Runs[run.ScheduleID] = runs
+	return s.write(data)
+}
+
+func (s *FileScheduleStore) Runs(scheduleID string) ([]ScheduleRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	runs := data.Runs[scheduleID]
+	if runs == nil {
+		runs = []ScheduleRun{}
+	}
+	return runs, nil
+}
+
+func (s *FileScheduleStore) load() (*scheduleFile, error) {
+	data := &scheduleFile{}
+	content, err := os.ReadFile(s.filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := unmarshalNumbers(content, data); err != nil {
+			return nil, fmt.Errorf("failed to parse schedules %s: %v", s.filename, err)
+		}
+	}
+	if data.Schedules == nil {
+		data.Schedules = make(map[string]Schedule)
+	}
+	if data.Runs == nil {
+		data.Runs = make(map[string][]ScheduleRun)
+	}
+	return data, nil
+}
+
+func (s *FileScheduleStore) write(data *scheduleFile) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.filename + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.filename)
+}
@@ -0,0 +1,281 @@
+package boilingdata
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scheduler runs the enabled schedules of its store when their cron expression matches and writes
+// the results to files in dir/<schedule id>/
+type Scheduler struct {
+	store     ScheduleStore
+	dir       string
+	mu        sync.Mutex
+	running   map[string]bool
+	instances map[string]*Instance
+	stop      chan struct{}
+}
+
+func NewScheduler(store ScheduleStore, dir string) (*Scheduler, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Scheduler{
+		store:     store,
+		dir:       dir,
+		running:   make(map[string]bool),
+		instances: make(map[string]*Instance),
+	}, nil
+}
+
+// DefaultSnapshotDir returns ~/.boilingdata/snapshots
+func DefaultSnapshotDir() string {
+	return defaultConfigPath("snapshots")
+}
+
+var schedulerMu sync.RWMutex
+var scheduler *Scheduler
+
+// SetScheduler sets the scheduler used by the server, nil disables schedules
+func SetScheduler(s *Scheduler) {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+	scheduler = s
+}
+
+// GetScheduler returns the scheduler of the server, nil when schedules are disabled
+func GetScheduler() *Scheduler {
+	schedulerMu.RLock()
+	defer schedulerMu.RUnlock()
+	return scheduler
+}
+
+func (s *Scheduler) Store() ScheduleStore {
+	return s.store
+}
+
+// Start checks the schedules at the start of every minute until Stop is called
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	if s.stop != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.stop = make(chan struct{})
+	stop := s.stop
+	s.mu.Unlock()
+	go func() {
+		for {
+			now := time.Now()
+			timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case minute := <-timer.C:
+				s.tick(minute.Truncate(time.Minute))
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	for _, instance := range s.instances {
+		instance.Wsc.Close()
+	}
+	s.instances = make(map[string]*Instance)
+}
+
+func (s *Scheduler) tick(minute time.Time) {
+	schedules, err := s.store.List()
+	if err != nil {
+		log.Println("Could not read schedules: " + err.Error())
+		return
+	}
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+		cron, err := ParseCron(schedule.Cron)
+		if err != nil || !cron.Match(minute) {
+			continue
+		}
+		go func(schedule Schedule) {
+			if _, err := s.Run(schedule.ID, TriggerCron); err != nil {
+				log.Printf("Schedule %s failed -> %v", schedule.ID, err)
+			}
+		}(schedule)
+	}
+}
+
+// Run runs the schedule now and records the run. A schedule is not run again while it is running.
+func (s *Scheduler) Run(id string, trigger string) (ScheduleRun, error) {
+	schedule, ok, err := s.store.Get(id)
+	if err != nil {
+		return ScheduleRun{}, err
+	}
+	if !ok {
+		return ScheduleRun{}, fmt.Errorf("no schedule %s", id)
+	}
+	s.mu.Lock()
+	if s.running[id] {
+		s.mu.Unlock()
+		return ScheduleRun{}, fmt.Errorf("schedule %s is already running", id)
+	}
+	s.running[id] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, id)
+		s.mu.Unlock()
+	}()
+
+	run := ScheduleRun{ID: newRequestID(), ScheduleID: id, Trigger: trigger, Start: time.Now().UTC(), Status: HistoryStatusSuccess}
+	result, file, err := s.execute(schedule, run.ID)
+	run.End = time.Now().UTC()
+	if result != nil {
+		run.RequestID = result.RequestID
+		run.Rows = len(result.Rows)
+	}
+	run.File = file
+	if err != nil {
+		run.Status = HistoryStatusError
+		run.Error = err.Error()
+	}
+	if err := s.store.AddRun(run); err != nil {
+		log.Println("Could not record schedule run: " + err.Error())
+	}
+	return run, err
+}
+
+// execute runs the saved query of the schedule as the user of its credential and writes the result file of the run
+func (s *Scheduler) execute(schedule Schedule, runID string) (*Result, string, error) {
+	store := SavedQueries()
+	if store == nil {
+		return nil, "", fmt.Errorf("saved queries are not enabled")
+	}
+	instance, err := s.instance(schedule)
+	if err != nil {
+		return nil, "", err
+	}
+	saved, ok, err := ResolveSavedQuery(store, instance.Auth.UserName(), schedule.SavedQueryOwner, schedule.SavedQuery, schedule.Version)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "", fmt.Errorf("no saved query %s", schedule.SavedQuery)
+	}
+	query, err := saved.Query(schedule.Params)
+	if err != nil {
+		return nil, "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), schedule.timeout())
+	defer cancel()
+	result, err := instance.Execute(ctx, query)
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := s.writeSnapshot(schedule, runID, result)
+	return result, file, err
+}
+
+// instance returns the instance of the user of the credential, which must be the owner of the schedule
+func (s *Scheduler) instance(schedule Schedule) (*Instance, error) {
+	provider, err := schedule.Credential.Provider()
+	if err != nil {
+		return nil, err
+	}
+	creds, err := provider.Retrieve()
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve credential: %v", err)
+	}
+	if creds.UserName != schedule.Owner {
+		return nil, fmt.Errorf("credential is not of the schedule owner")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	instance, ok := s.instances[creds.UserName]
+	if !ok || instance.Auth.password != creds.Password {
+		if ok {
+			instance.Wsc.Close()
+		}
+		instance = newInstance(&Auth{userName: creds.UserName, password: creds.Password})
+		s.instances[creds.UserName] = instance
+	}
+	return instance, nil
+}
+
+// writeSnapshot writes the result to a new file named by the time and the id of the run and applies the retention
+func (s *Scheduler) writeSnapshot(schedule Schedule, runID string, result *Result) (string, error) {
+	dir := filepath.Join(s.dir, schedule.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, time.Now().UTC().Format("20060102T150405Z")+"-"+runID+"."+schedule.Format)
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if err := result.Write(file, schedule.Format); err != nil {
+		file.Close()
+		os.Remove(name)
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	s.applyRetention(dir, schedule.Retention)
+	return name, nil
+}
+
+func (s *Scheduler) applyRetention(dir string, retention SnapshotRetention) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	// Names start with timestamps, newest last
+	sort.Strings(names)
+	keep := len(names)
+	if retention.MaxFiles > 0 && keep > retention.MaxFiles {
+		keep = retention.MaxFiles
+	}
+	cutoff := time.Now().Add(-time.Duration(retention.MaxAgeHours) * time.Hour)
+	for i, name := range names {
+		expired := retention.MaxAgeHours > 0
+		if expired {
+			if info, err := os.Stat(filepath.Join(dir, name)); err != nil || !info.ModTime().Before(cutoff) {
+				expired = false
+			}
+		}
+		if i < len(names)-keep || expired {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+}
+
+// RemoveSnapshots deletes the result files of a schedule
+func (s *Scheduler) RemoveSnapshots(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return fmt.Errorf("invalid schedule id")
+	}
+	return os.RemoveAll(filepath.Join(s.dir, id))
+}
@@ -0,0 +1,42 @@
+package boilingdata
+
+import "time"
+
+// SessionInfo describes who an instance is logged in as and the state of its session
+type SessionInfo struct {
+	UserName                    string                 `json:"userName"`
+	AuthMode                    string                 `json:"authMode"`
+	Claims                      map[string]interface{} `json:"claims,omitempty"`
+	TokenExpiresAt              *time.Time             `json:"tokenExpiresAt,omitempty"`
+	AwsCredentialsExpiresAt     *time.Time             `json:"awsCredentialsExpiresAt,omitempty"`
+	WebSocket                   string                 `json:"webSocket"`
+	WebSocketError              string                 `json:"webSocketError,omitempty"`
+	IdleTimeoutRemainingSeconds int                    `json:"idleTimeoutRemainingSeconds"`
+	InFlightQueries             int                    `json:"inFlightQueries"`
+}
+
+func (instance *Instance) SessionInfo() SessionInfo {
+	info := SessionInfo{
+		UserName:                    instance.Auth.UserName(),
+		AuthMode:                    "cognito",
+		WebSocket:                   "connected",
+		IdleTimeoutRemainingSeconds: int(instance.Wsc.IdleTimeoutRemaining().Seconds()),
+		InFlightQueries:             instance.Wsc.InFlight(),
+	}
+	if instance.Auth.IsIAMMode() {
+		info.AuthMode = "iam"
+	} else if claims, err := instance.Auth.IdTokenClaims(); err == nil {
+		info.Claims = claims
+	}
+	if t := instance.Auth.TokenExpiresAt(); !t.IsZero() {
+		info.TokenExpiresAt = &t
+	}
+	if t := instance.Auth.AwsCredentialsExpiresAt(); !t.IsZero() {
+		info.AwsCredentialsExpiresAt = &t
+	}
+	if instance.Wsc.IsWebSocketClosed() {
+		info.WebSocket = "closed"
+		info.WebSocketError = instance.Wsc.Error
+	}
+	return info
+}
@@ -0,0 +1,86 @@
+package boilingdata
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup shares one websocket request between concurrent callers of identical queries
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done    chan struct{}
+	result  *Result
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+	// listeners of the waiting callers get the progress of the shared request
+	listeners    map[int]func(Progress)
+	nextListener int
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// do runs fn once for all concurrent callers with the same key and hands its result to all of them.
+// A caller whose ctx is done stops waiting without cancelling the shared call, which is cancelled only
+// once every caller stopped waiting. shared is true for callers that joined a call of another caller.
+// The progress fn reports is passed on to the onProgress of every caller.
+func (g *flightGroup) do(ctx context.Context, key string, onProgress func(Progress), fn func(ctx context.Context, onProgress func(Progress)) (*Result, error)) (result *Result, shared bool, err error) {
+	g.mu.Lock()
+	call, shared := g.calls[key]
+	if !shared {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall{done: make(chan struct{}), cancel: cancel, listeners: make(map[int]func(Progress))}
+		g.calls[key] = call
+		go func() {
+			call.result, call.err = fn(callCtx, func(progress Progress) {
+				g.mu.Lock()
+				listeners := make([]func(Progress), 0, len(call.listeners))
+				for _, listener := range call.listeners {
+					listeners = append(listeners, listener)
+				}
+				g.mu.Unlock()
+				for _, listener := range listeners {
+					listener(progress)
+				}
+			})
+			g.mu.Lock()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	listener := call.nextListener
+	call.nextListener++
+	if onProgress != nil {
+		call.listeners[listener] = onProgress
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result, shared, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		delete(call.listeners, listener)
+		if call.waiters == 0 {
+			// Nobody waits for the result anymore, later callers start a new request
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, shared, ctx.Err()
+	}
+}
@@ -0,0 +1,89 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+	"github.com/pavi6691/go-boilingdata/wsclient"
+)
+
+var errNotSupported = errors.New("boilingdata: only queries are supported")
+
+type conn struct {
+	client *boilingdata.Client
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("boilingdata: named arguments are not supported, use ? or $1 placeholders")
+		}
+		values[i] = arg.Value
+	}
+	result, err := c.client.Query(ctx, query, values...)
+	if errors.Is(err, wsclient.ErrNoResult) {
+		return emptyRows(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newRows(result), nil
+}
+
+// CheckNamedValue passes arguments through as they are, they are formatted by boilingdata.Bind
+func (c *conn) CheckNamedValue(arg *driver.NamedValue) error {
+	if valuer, ok := arg.Value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		arg.Value = v
+	}
+	return nil
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+// Close does nothing, the websocket is shared by all connections and closed with the sql.DB
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, errors.New("boilingdata: transactions are not supported")
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1, the number of placeholders is not checked by database/sql
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errNotSupported
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return s.QueryContext(context.Background(), named)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
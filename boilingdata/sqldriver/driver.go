@@ -0,0 +1,137 @@
+// Package sqldriver registers a "boilingdata" driver for database/sql.
+//
+//	db, err := sql.Open("boilingdata", "boilingdata://me%40example.com:secret@/?timeout=1m")
+//	rows, err := db.QueryContext(ctx, "SELECT * FROM parquet_scan('s3://boilingdata-demo/demo.parquet') LIMIT 10")
+//
+// The DSN is a URL, the credentials are either part of it or taken from the source query parameter:
+//
+//	source           chain (default), env, file or secrets, see boilingdata.DefaultCredentialChain
+//	profile          profile in the credentials or secrets file
+//	credentialsFile  path of the credentials file
+//	secretsFile      path of the encrypted secrets file
+//	config           path of a JSON file with the boilingdata.Config
+//	timeout          timeout of queries without a deadline, e.g. 30s
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+)
+
+func init() {
+	sql.Register("boilingdata", &Driver{})
+}
+
+type Driver struct{}
+
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	c, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	opts, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &connector{driver: d, opts: opts}, nil
+}
+
+// ParseDSN turns a DSN into the options of the boilingdata client
+func ParseDSN(dsn string) ([]boilingdata.Option, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid boilingdata dsn: %v", err)
+	}
+	if u.Scheme != "boilingdata" {
+		return nil, fmt.Errorf("invalid boilingdata dsn: scheme must be boilingdata")
+	}
+	params := u.Query()
+	var opts []boilingdata.Option
+
+	var provider boilingdata.CredentialProvider
+	profile := params.Get("profile")
+	switch source := params.Get("source"); source {
+	case "", "chain":
+		provider = boilingdata.NewChainProvider(
+			&boilingdata.EnvProvider{},
+			&boilingdata.FileProvider{Filename: params.Get("credentialsFile"), Profile: profile},
+			&boilingdata.EncryptedFileProvider{Filename: params.Get("secretsFile"), Profile: profile},
+		)
+	case "env":
+		provider = &boilingdata.EnvProvider{}
+	case "file":
+		provider = &boilingdata.FileProvider{Filename: params.Get("credentialsFile"), Profile: profile}
+	case "secrets":
+		provider = &boilingdata.EncryptedFileProvider{Filename: params.Get("secretsFile"), Profile: profile}
+	default:
+		return nil, fmt.Errorf("invalid boilingdata dsn: unknown credential source %s", source)
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		provider = boilingdata.NewStaticProvider(u.User.Username(), password)
+	}
+	opts = append(opts, boilingdata.WithCredentials(provider))
+
+	if configFile := params.Get("config"); configFile != "" {
+		cfg, err := boilingdata.LoadConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, boilingdata.WithConfig(cfg))
+	}
+	if timeout := params.Get("timeout"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boilingdata dsn: timeout %v", err)
+		}
+		opts = append(opts, boilingdata.WithTimeout(d))
+	}
+	return opts, nil
+}
+
+// connector shares one client, and so one websocket, between the connections of a sql.DB
+type connector struct {
+	driver *Driver
+	opts   []boilingdata.Option
+	mu     sync.Mutex
+	client *boilingdata.Client
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		client, err := boilingdata.NewClient(c.opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
+	}
+	return &conn{client: c.client}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close is called by sql.DB.Close
+func (c *connector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+	return nil
+}
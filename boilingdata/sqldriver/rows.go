@@ -0,0 +1,121 @@
+package sqldriver
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+type rows struct {
+	result  *boilingdata.Result
+	columns []string
+	// types are the types of the columns, BIGINT columns with values beyond int64 are VARCHAR
+	types []string
+	index int
+}
+
+func newRows(result *boilingdata.Result) *rows {
+	r := &rows{result: result, columns: result.Columns()}
+	r.types = make([]string, len(r.columns))
+	for i, column := range r.columns {
+		r.types[i] = result.Schema.Columns[i].Type
+		if r.types[i] == models.TypeBigint && !fitsInt64(result.Rows, column) {
+			r.types[i] = models.TypeVarchar
+		}
+	}
+	return r
+}
+
+// emptyRows are the rows of a query that returned no rows, they have no columns as the columns are
+// only known from the rows
+func emptyRows() *rows {
+	return newRows(&boilingdata.Result{Schema: models.NewSchema()})
+}
+
+func fitsInt64(data []map[string]interface{}, column string) bool {
+	for _, row := range data {
+		if n, ok := row[column].(json.Number); ok {
+			if _, err := n.Int64(); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	r.index = len(r.result.Rows)
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.index >= len(r.result.Rows) {
+		return io.EOF
+	}
+	row := r.result.Rows[r.index]
+	r.index++
+	for i, column := range r.columns {
+		value, err := driverValue(row[column], r.types[i])
+		if err != nil {
+			return err
+		}
+		dest[i] = value
+	}
+	return nil
+}
+
+// ColumnTypeDatabaseTypeName reports the type inferred in the schema of the result
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.result.Schema.Columns[index].Type
+}
+
+// ColumnTypeScanType reports the type of the non NULL values Next returns for the column
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	switch r.types[index] {
+	case models.TypeBigint:
+		return reflect.TypeOf(int64(0))
+	case models.TypeDecimal, models.TypeVarchar:
+		return reflect.TypeOf("")
+	case models.TypeBoolean:
+		return reflect.TypeOf(false)
+	case models.TypeStruct, models.TypeList, models.TypeJSON:
+		return reflect.TypeOf([]byte{})
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *rows) ColumnTypeNullable(index int) (nullable bool, ok bool) {
+	return true, true
+}
+
+// driverValue converts a decoded JSON value of a column of the type. Integers are returned as int64,
+// decimals and integers of VARCHAR columns as their exact text and values of STRUCT, LIST and JSON
+// columns as JSON
+func driverValue(v interface{}, typ string) (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch typ {
+	case models.TypeStruct, models.TypeList, models.TypeJSON:
+		return json.Marshal(v)
+	}
+	switch v := v.(type) {
+	case float64, string, bool:
+		return v, nil
+	case json.Number:
+		if typ == models.TypeBigint {
+			return v.Int64()
+		}
+		return v.String(), nil
+	default:
+		return json.Marshal(v)
+	}
+}
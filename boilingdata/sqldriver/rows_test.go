@@ -0,0 +1,86 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+	"github.com/pavi6691/go-boilingdata/internal/wstest"
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// openTestDB returns a sql.DB whose queries are answered with the rows returned by answer
+func openTestDB(t *testing.T, answer func(sql string) []map[string]interface{}) *sql.DB {
+	url := wstest.NewServer(t, func(_ int, payload models.Payload) interface{} {
+		return wstest.Data(payload, answer(payload.SQL))
+	})
+	cfg := boilingdata.DefaultConfig()
+	cfg.WssURL = url
+	db := sql.OpenDB(&connector{driver: &Driver{}, opts: []boilingdata.Option{
+		boilingdata.WithConfig(cfg),
+		boilingdata.WithIAMCredentials(credentials.NewStaticCredentials("AKID", "SECRET", "")),
+		boilingdata.WithRetryPolicy(boilingdata.RetryPolicy{MaxAttempts: 1}),
+	}})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestQueryWithoutRows(t *testing.T) {
+	db := openTestDB(t, func(string) []map[string]interface{} {
+		return []map[string]interface{}{}
+	})
+	rows, err := db.QueryContext(context.Background(), "SELECT * FROM t WHERE false")
+	if err != nil {
+		t.Fatalf("QueryContext: %v", err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		t.Error("got a row, want none")
+	}
+	if err := rows.Err(); err != nil {
+		t.Errorf("rows.Err: %v", err)
+	}
+}
+
+func TestColumnScanTypes(t *testing.T) {
+	db := openTestDB(t, func(string) []map[string]interface{} {
+		return []map[string]interface{}{
+			{"small": 1, "big": 1, "mixed": "a", "nested": map[string]interface{}{"a": 1}},
+			{"small": 2, "big": json.Number("18446744073709551615"), "mixed": 1, "nested": nil},
+		}
+	})
+	rows, err := db.QueryContext(context.Background(), "SELECT * FROM t")
+	if err != nil {
+		t.Fatalf("QueryContext: %v", err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(types))
+		pointers := make([]interface{}, len(types))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			t.Fatal(err)
+		}
+		for i, value := range values {
+			if value == nil {
+				continue
+			}
+			if got, want := reflect.TypeOf(value), types[i].ScanType(); got != want {
+				t.Errorf("column %s: value %v is %v, scan type is %v", types[i].Name(), value, got, want)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Errorf("rows.Err: %v", err)
+	}
+}
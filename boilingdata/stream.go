@@ -0,0 +1,74 @@
+package boilingdata
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// StreamHandler receives the result of a query as the parts of its response arrive. Schema is called once
+// before the first rows with the columns of the first part, later parts can add columns. Nil funcs are skipped.
+type StreamHandler struct {
+	Schema   func(schema *models.Schema)
+	Rows     func(rows []map[string]interface{})
+	Progress func(Progress)
+}
+
+// Stream executes the query handing its rows to the handler in the order the parts arrive. The complete
+// result is returned at the end as with Execute. Streamed queries are not shared with identical queries
+// and are not retried once rows were handed out.
+func (instance *Instance) Stream(ctx context.Context, q Query, handler StreamHandler) (*Result, error) {
+	q.stream = &partStreamer{handler: handler, sent: make(map[partKey]bool)}
+	if handler.Progress != nil {
+		q.onProgress = handler.Progress
+	}
+	return instance.Execute(ctx, q)
+}
+
+type partKey struct {
+	batch, split, subBatch int
+}
+
+// partStreamer hands the rows of the parts not streamed yet to the handler
+type partStreamer struct {
+	handler    StreamHandler
+	sent       map[partKey]bool
+	schemaSent bool
+}
+
+func (s *partStreamer) parts(parts []*models.Response) {
+	var fresh []*models.Response
+	for _, p := range parts {
+		key := partKey{p.BatchSerial, p.SplitSerial, p.SubBatchSerial}
+		if !s.sent[key] {
+			s.sent[key] = true
+			fresh = append(fresh, p)
+		}
+	}
+	sort.Slice(fresh, func(i, j int) bool {
+		a, b := fresh[i], fresh[j]
+		if a.BatchSerial != b.BatchSerial {
+			return a.BatchSerial < b.BatchSerial
+		}
+		if a.SplitSerial != b.SplitSerial {
+			return a.SplitSerial < b.SplitSerial
+		}
+		return a.SubBatchSerial < b.SubBatchSerial
+	})
+	for _, p := range fresh {
+		s.rows(p.Schema, p.Data)
+	}
+}
+
+func (s *partStreamer) rows(schema *models.Schema, rows []map[string]interface{}) {
+	if !s.schemaSent {
+		s.schemaSent = true
+		if s.handler.Schema != nil {
+			s.handler.Schema(schema)
+		}
+	}
+	if len(rows) > 0 && s.handler.Rows != nil {
+		s.handler.Rows(rows)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/pavi6691/go-boilingdata/boilingdata"
+)
+
+// Encrypts a plain credentials file so it can be used as ~/.boilingdata/secrets.enc
+func main() {
+	in := flag.String("in", "", "plain credentials file")
+	out := flag.String("out", "secrets.enc", "encrypted secrets file to write")
+	flag.Parse()
+
+	passphrase := os.Getenv(boilingdata.EnvSecretsPassphrase)
+	if *in == "" || passphrase == "" {
+		log.Fatalf("usage: %s=<passphrase> encrypt-secrets -in credentials -out secrets.enc", boilingdata.EnvSecretsPassphrase)
+	}
+	plain, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", *in, err)
+	}
+	encrypted, err := boilingdata.EncryptSecrets(plain, passphrase)
+	if err != nil {
+		log.Fatalf("failed to encrypt: %v", err)
+	}
+	if err := os.WriteFile(*out, encrypted, 0600); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+}
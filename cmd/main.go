@@ -1,18 +1,124 @@
 package main
 
 import (
+	"errors"
+	"expvar"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/pavi6691/go-boilingdata/api"
+	"github.com/pavi6691/go-boilingdata/boilingdata"
 )
 
 func main() {
+	if configFile := os.Getenv("BD_CONFIG"); configFile != "" {
+		cfg, err := boilingdata.LoadConfig(configFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		boilingdata.SetDefaultConfig(cfg)
+	}
+	if auditLog := os.Getenv("BD_AUDIT_LOG"); auditLog != "" {
+		sink, err := boilingdata.NewJSONLinesSink(auditLog, 10*1024*1024, 5)
+		if err != nil {
+			log.Fatalf("Could not open audit log %s: %v", auditLog, err)
+		}
+		defer sink.Close()
+		boilingdata.SetAuditSink(sink)
+	}
+	historyFile := os.Getenv("BD_HISTORY_FILE")
+	if historyFile == "" {
+		historyFile = boilingdata.DefaultHistoryFile()
+	}
+	if historyFile != "off" {
+		store, err := boilingdata.NewFileHistoryStore(historyFile, boilingdata.DefaultHistoryMaxEntries)
+		if err != nil {
+			log.Fatalf("Could not open query history %s: %v", historyFile, err)
+		}
+		boilingdata.SetHistoryStore(store)
+	}
+	savedQueriesFile := os.Getenv("BD_SAVED_QUERIES_FILE")
+	if savedQueriesFile == "" {
+		savedQueriesFile = boilingdata.DefaultSavedQueriesFile()
+	}
+	if savedQueriesFile != "off" {
+		store, err := boilingdata.NewFileSavedQueryStore(savedQueriesFile)
+		if err != nil {
+			log.Fatalf("Could not open saved queries %s: %v", savedQueriesFile, err)
+		}
+		boilingdata.SetSavedQueryStore(store)
+	}
+	schedulesFile := os.Getenv("BD_SCHEDULES_FILE")
+	if schedulesFile == "" {
+		schedulesFile = boilingdata.DefaultSchedulesFile()
+	}
+	if schedulesFile != "off" {
+		store, err := boilingdata.NewFileScheduleStore(schedulesFile)
+		if err != nil {
+			log.Fatalf("Could not open schedules %s: %v", schedulesFile, err)
+		}
+		snapshotDir := os.Getenv("BD_SNAPSHOT_DIR")
+		if snapshotDir == "" {
+			snapshotDir = boilingdata.DefaultSnapshotDir()
+		}
+		scheduler, err := boilingdata.NewScheduler(store, snapshotDir)
+		if err != nil {
+			log.Fatalf("Could not create snapshot directory %s: %v", snapshotDir, err)
+		}
+		scheduler.Start()
+		defer scheduler.Stop()
+		boilingdata.SetScheduler(scheduler)
+	}
+	boilingdata.SetJobManager(boilingdata.NewJobManager(time.Duration(boilingdata.DefaultConfig().JobRetentionMinutes) * time.Minute))
+	cursors, err := boilingdata.NewCursorStore(boilingdata.DefaultConfig().Cursors)
+	if err != nil {
+		log.Fatalf("Could not create cursor spill directory: %v", err)
+	}
+	boilingdata.SetCursorStore(cursors)
 	handler := &api.Handler{}
-	http.HandleFunc("/login", handler.Login)
-	http.HandleFunc("/connect", handler.ConnectWSS)
-	http.HandleFunc("/query", handler.Query)
-	http.HandleFunc("/wssurl", handler.GetSignedWSSUrl)
+	if err := handler.LoginWithProvider(boilingdata.DefaultCredentialChain()); err != nil {
+		if !errors.Is(err, boilingdata.ErrNoCredentials) {
+			log.Println("Could not login with configured credentials -> " + err.Error())
+		}
+	}
+	if metricsAddr := os.Getenv("BD_METRICS_ADDR"); metricsAddr != "off" {
+		if metricsAddr == "" {
+			metricsAddr = "localhost:8089"
+		}
+		// Metrics are served on their own listener, not next to the API
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/debug/vars", expvar.Handler())
+		go func() {
+			log.Println("Metrics are served on " + metricsAddr)
+			if err := http.ListenAndServe(metricsAddr, metricsMux); err != nil {
+				log.Println("Could not serve metrics -> " + err.Error())
+			}
+		}()
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", handler.Login)
+	mux.HandleFunc("/logout", handler.Logout)
+	mux.HandleFunc("/connect", handler.ConnectWSS)
+	mux.HandleFunc("/query", handler.Query)
+	mux.HandleFunc("/query/stream", handler.QueryStream)
+	mux.HandleFunc("/wssurl", handler.GetSignedWSSUrl)
+	mux.HandleFunc("/me", handler.Me)
+	mux.HandleFunc("/cache/stats", handler.CacheStats)
+	mux.HandleFunc("/history", handler.History)
+	mux.HandleFunc("/history/{id}/rerun", handler.RerunHistory)
+	mux.HandleFunc("/saved-queries", handler.SavedQueries)
+	mux.HandleFunc("/saved-queries/{name}", handler.SavedQuery)
+	mux.HandleFunc("/saved-queries/{name}/versions", handler.SavedQueryVersions)
+	mux.HandleFunc("/saved-queries/{name}/run", handler.RunSavedQuery)
+	mux.HandleFunc("/schedules", handler.Schedules)
+	mux.HandleFunc("/schedules/{id}", handler.Schedule)
+	mux.HandleFunc("/schedules/{id}/run", handler.RunSchedule)
+	mux.HandleFunc("/schedules/{id}/runs", handler.ScheduleRuns)
+	mux.HandleFunc("/jobs", handler.Jobs)
+	mux.HandleFunc("/jobs/{id}", handler.Job)
+	mux.HandleFunc("/jobs/{id}/result", handler.JobResult)
 	log.Println("Server is running on port 8088...")
-	http.ListenAndServe(":8088", nil)
+	http.ListenAndServe(":8088", mux)
 }
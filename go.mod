@@ -7,6 +7,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/config v1.27.11
 	github.com/aws/aws-sdk-go-v2/service/cognitoidentity v1.23.7
 	github.com/gorilla/websocket v1.5.1
+	golang.org/x/crypto v0.14.0
 )
 
 require (
@@ -0,0 +1,57 @@
+// Package wstest runs fake BoilingData websocket servers for tests
+package wstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/pavi6691/go-boilingdata/models"
+)
+
+// Handler returns the message sent back for a query, nil drops the connection. conn counts the
+// connections to the server, starting at 1.
+type Handler func(conn int, payload models.Payload) interface{}
+
+// NewServer starts a websocket server answering queries with handler and returns its ws:// url.
+// The server is closed when the test ends.
+func NewServer(t testing.TB, handler Handler) string {
+	var connections int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		n := int(atomic.AddInt32(&connections, 1))
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var payload models.Payload
+			json.Unmarshal(message, &payload)
+			answer := handler(n, payload)
+			if answer == nil {
+				return
+			}
+			conn.WriteJSON(answer)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// Data returns a DATA message with the rows as answer to the query
+func Data(payload models.Payload, rows interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"messageType": "DATA",
+		"requestId":   payload.RequestID,
+		"data":        rows,
+	}
+}
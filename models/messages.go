@@ -1,13 +1,44 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
+// CacheMode tells BoilingData whether results may be read from its cache
+type CacheMode string
+
+const (
+	CacheNone     CacheMode = "NONE"
+	CacheRefresh  CacheMode = "REFRESH"
+	CacheNoExpire CacheMode = "NO_EXPIRE"
+)
+
+var CacheModes = []CacheMode{CacheNone, CacheRefresh, CacheNoExpire}
+
+func (m CacheMode) Validate() error {
+	for _, mode := range CacheModes {
+		if m == mode {
+			return nil
+		}
+	}
+	names := make([]string, len(CacheModes))
+	for i, mode := range CacheModes {
+		names[i] = string(mode)
+	}
+	return fmt.Errorf("invalid readCache %q, supported values are %s", m, strings.Join(names, ", "))
+}
+
 type Payload struct {
-	MessageType string `json:"messageType"`
-	SQL         string `json:"sql"`
-	RequestID   string `json:"requestId"`
-	ReadCache   string `json:"readCache"`
-	Tags        []Tag  `json:"tags"`
+	MessageType string    `json:"messageType"`
+	SQL         string    `json:"sql"`
+	RequestID   string    `json:"requestId"`
+	ReadCache   CacheMode `json:"readCache"`
+	Tags        []Tag     `json:"tags"`
 }
 
+// Response is a message received from the websocket. Numbers in Data are decoded as json.Number
+// so that large integers and decimals keep their precision.
 type Response struct {
 	MessageType       string                   `json:"messageType"`
 	RequestID         string                   `json:"requestId"`
@@ -19,7 +50,13 @@ type Response struct {
 	SubBatchSerial    int                      `json:"subBatchSerial"`
 	TotalSubBatches   int                      `json:"totalSubBatches"`
 	Data              []map[string]interface{} `json:"data"`
-	Keys              []string                 `json:"-"`
+	Schema            *Schema                  `json:"schema,omitempty"`
+	// Error is the message of an ERROR response
+	Error string `json:"error,omitempty"`
+	// CacheInfos has the cache info of every part of an assembled response
+	CacheInfos []string `json:"-"`
+	// Bytes is the size of the received message, of all parts for an assembled response
+	Bytes int64 `json:"-"`
 }
 
 // Define structs to represent the JSON payload
@@ -28,21 +65,16 @@ type Tag struct {
 	Value string `json:"value"`
 }
 
-func GetPayLoad() Payload {
+// GetPayLoad returns a SQL query payload with the given tags, no tags are sent if none are given
+func GetPayLoad(tags ...Tag) Payload {
+	if tags == nil {
+		tags = []Tag{}
+	}
 	return Payload{
 		MessageType: "SQL_QUERY",
 		SQL:         "",
 		RequestID:   "",
-		ReadCache:   "NONE",
-		Tags: []Tag{
-			{
-				Name:  "CostCenter",
-				Value: "930",
-			},
-			{
-				Name:  "ProjectId",
-				Value: "Top secret Area 53",
-			},
-		},
+		ReadCache:   CacheNone,
+		Tags:        tags,
 	}
 }
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Column types inferred from the JSON values of a column
+const (
+	TypeNull    = "NULL"
+	TypeBigint  = "BIGINT"
+	TypeDecimal = "DECIMAL"
+	TypeVarchar = "VARCHAR"
+	TypeBoolean = "BOOLEAN"
+	TypeStruct  = "STRUCT"
+	TypeList    = "LIST"
+	// TypeJSON is used when a column has values of different types
+	TypeJSON = "JSON"
+)
+
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Schema is the ordered list of columns of a result, the union of the columns of all its rows
+type Schema struct {
+	Columns []Column `json:"columns"`
+	index   map[string]int
+}
+
+func NewSchema() *Schema {
+	return &Schema{index: make(map[string]int)}
+}
+
+// Names returns the column names in order
+func (s *Schema) Names() []string {
+	if s == nil {
+		return nil
+	}
+	names := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func (s *Schema) Column(name string) (Column, bool) {
+	if s == nil {
+		return Column{}, false
+	}
+	s.buildIndex()
+	i, ok := s.index[name]
+	if !ok {
+		return Column{}, false
+	}
+	return s.Columns[i], true
+}
+
+// Add records a value of the column, appending the column if it is new and widening its type
+func (s *Schema) Add(name string, value interface{}) {
+	s.addType(name, typeOf(value))
+}
+
+// Merge adds the columns of other after the columns already known
+func (s *Schema) Merge(other *Schema) {
+	if other == nil {
+		return
+	}
+	for _, c := range other.Columns {
+		s.addType(c.Name, c.Type)
+	}
+}
+
+func (s *Schema) addType(name string, typ string) {
+	s.buildIndex()
+	i, ok := s.index[name]
+	if !ok {
+		s.index[name] = len(s.Columns)
+		s.Columns = append(s.Columns, Column{Name: name, Type: typ})
+		return
+	}
+	s.Columns[i].Type = widen(s.Columns[i].Type, typ)
+}
+
+// buildIndex rebuilds the name index, e.g. after the schema was unmarshalled
+func (s *Schema) buildIndex() {
+	if s.index != nil && len(s.index) == len(s.Columns) {
+		return
+	}
+	s.index = make(map[string]int, len(s.Columns))
+	for i, c := range s.Columns {
+		s.index[c.Name] = i
+	}
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return TypeNull
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return TypeDecimal
+		}
+		return TypeBigint
+	case float64:
+		return TypeDecimal
+	case string:
+		return TypeVarchar
+	case bool:
+		return TypeBoolean
+	case map[string]interface{}:
+		return TypeStruct
+	case []interface{}:
+		return TypeList
+	}
+	return TypeJSON
+}
+
+// textRank orders the types whose values keep their exact text when widened, BIGINT to DECIMAL to VARCHAR
+var textRank = map[string]int{TypeBigint: 1, TypeDecimal: 2, TypeVarchar: 3}
+
+func widen(a string, b string) string {
+	switch {
+	case a == b || b == TypeNull:
+		return a
+	case a == TypeNull:
+		return b
+	case textRank[a] > 0 && textRank[b] > 0:
+		if textRank[a] > textRank[b] {
+			return a
+		}
+		return b
+	}
+	return TypeJSON
+}
+
+// DecodeRows decodes the JSON array of row objects of a response with a token level decoder, so the
+// column order is kept as sent. Numbers are decoded as json.Number.
+func DecodeRows(data json.RawMessage) ([]map[string]interface{}, *Schema, error) {
+	schema := NewSchema()
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, schema, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := expectDelim(decoder, '['); err != nil {
+		return nil, nil, err
+	}
+	rows := []map[string]interface{}{}
+	for decoder.More() {
+		if err := expectDelim(decoder, '{'); err != nil {
+			return nil, nil, err
+		}
+		row := make(map[string]interface{})
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, nil, err
+			}
+			key, ok := token.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("expected column name, got %v", token)
+			}
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, nil, err
+			}
+			row[key] = value
+			schema.Add(key, value)
+		}
+		if err := expectDelim(decoder, '}'); err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, row)
+	}
+	if err := expectDelim(decoder, ']'); err != nil {
+		return nil, nil, err
+	}
+	return rows, schema, nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %v in rows, got %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeRows(t *testing.T) {
+	data := json.RawMessage(`[
+		{"z": 1, "a": "x", "nested": {"b": [1, {"c": "\"}"}]}, "list": [1, 2], "empty": null},
+		{"a": "y", "z": 2, "late": true, "empty": null}
+	]`)
+	rows, schema, err := DecodeRows(data)
+	if err != nil {
+		t.Fatalf("DecodeRows: %v", err)
+	}
+	want := []Column{
+		{"z", TypeBigint},
+		{"a", TypeVarchar},
+		{"nested", TypeStruct},
+		{"list", TypeList},
+		{"empty", TypeNull},
+		{"late", TypeBoolean},
+	}
+	if !reflect.DeepEqual(schema.Columns, want) {
+		t.Errorf("columns = %v, want %v", schema.Columns, want)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0]["z"] != json.Number("1") {
+		t.Errorf("z = %#v, want json.Number 1", rows[0]["z"])
+	}
+	nested := map[string]interface{}{"b": []interface{}{json.Number("1"), map[string]interface{}{"c": `"}`}}}
+	if !reflect.DeepEqual(rows[0]["nested"], nested) {
+		t.Errorf("nested = %#v", rows[0]["nested"])
+	}
+	if _, ok := rows[1]["nested"]; ok {
+		t.Error("column missing from a row was added to it")
+	}
+}
+
+func TestDecodeRowsEmpty(t *testing.T) {
+	for _, data := range []string{"", "null", "[]"} {
+		rows, schema, err := DecodeRows(json.RawMessage(data))
+		if err != nil || len(rows) != 0 || len(schema.Columns) != 0 {
+			t.Errorf("DecodeRows(%q) = %v, %v, %v", data, rows, schema, err)
+		}
+	}
+}
+
+func TestDecodeRowsErrors(t *testing.T) {
+	for _, data := range []string{`{"a": 1}`, `[1]`, `[{"a": 1}`, `[{"a": }]`, `[{"a": 1}}`} {
+		if _, _, err := DecodeRows(json.RawMessage(data)); err == nil {
+			t.Errorf("DecodeRows(%s) succeeded", data)
+		}
+	}
+}
+
+func TestSchemaWidening(t *testing.T) {
+	tests := []struct {
+		values []interface{}
+		want   string
+	}{
+		{[]interface{}{json.Number("1"), json.Number("2")}, TypeBigint},
+		{[]interface{}{json.Number("1"), json.Number("1.5")}, TypeDecimal},
+		{[]interface{}{json.Number("1e3"), json.Number("1")}, TypeDecimal},
+		{[]interface{}{json.Number("1"), json.Number("1.5"), "x"}, TypeVarchar},
+		{[]interface{}{"x", json.Number("1")}, TypeVarchar},
+		{[]interface{}{nil, json.Number("1"), nil}, TypeBigint},
+		{[]interface{}{nil, nil}, TypeNull},
+		{[]interface{}{true, false}, TypeBoolean},
+		{[]interface{}{true, "x"}, TypeJSON},
+		{[]interface{}{json.Number("1"), true}, TypeJSON},
+		{[]interface{}{map[string]interface{}{}, []interface{}{}}, TypeJSON},
+		{[]interface{}{"x", map[string]interface{}{}}, TypeJSON},
+		{[]interface{}{map[string]interface{}{}, nil}, TypeStruct},
+		{[]interface{}{[]interface{}{}, []interface{}{}}, TypeList},
+		{[]interface{}{json.Number("1"), true, json.Number("2")}, TypeJSON},
+	}
+	for _, test := range tests {
+		schema := NewSchema()
+		for _, value := range test.values {
+			schema.Add("c", value)
+		}
+		if column, _ := schema.Column("c"); column.Type != test.want {
+			t.Errorf("type of %v = %s, want %s", test.values, column.Type, test.want)
+		}
+	}
+}
+
+func TestSchemaMerge(t *testing.T) {
+	_, first, err := DecodeRows(json.RawMessage(`[{"id": 1, "amount": 1, "note": null}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, second, err := DecodeRows(json.RawMessage(`[{"extra": "x", "amount": 1.5, "id": 2, "note": "n"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Merge(second)
+	first.Merge(nil)
+	want := []Column{
+		{"id", TypeBigint},
+		{"amount", TypeDecimal},
+		{"note", TypeVarchar},
+		{"extra", TypeVarchar},
+	}
+	if !reflect.DeepEqual(first.Columns, want) {
+		t.Errorf("merged columns = %v, want %v", first.Columns, want)
+	}
+
+	// A schema read from JSON has no index yet
+	var decoded Schema
+	if err := json.Unmarshal([]byte(`{"columns": [{"name": "id", "type": "BIGINT"}]}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	decoded.Merge(second)
+	if names := decoded.Names(); !reflect.DeepEqual(names, []string{"id", "extra", "amount", "note"}) {
+		t.Errorf("names after merging into a decoded schema = %v", names)
+	}
+	if column, ok := decoded.Column("amount"); !ok || column.Type != TypeDecimal {
+		t.Errorf("amount = %v, %v", column, ok)
+	}
+}
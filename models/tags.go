@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeTags merges tag lists in order, a tag of a later list overrides the tag of the same name before it
+func MergeTags(lists ...[]Tag) []Tag {
+	merged := []Tag{}
+	index := make(map[string]int)
+	for _, tags := range lists {
+		for _, tag := range tags {
+			if i, ok := index[tag.Name]; ok {
+				merged[i] = tag
+				continue
+			}
+			index[tag.Name] = len(merged)
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+// TagPolicy lists tags every query must have and the values allowed for a tag
+type TagPolicy struct {
+	Required []string            `json:"required"`
+	Allowed  map[string][]string `json:"allowed"`
+}
+
+func (p TagPolicy) Validate(tags []Tag) error {
+	values := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		if tag.Name == "" {
+			return fmt.Errorf("tag name must not be empty")
+		}
+		values[tag.Name] = tag.Value
+	}
+	var missing []string
+	for _, name := range p.Required {
+		if values[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required tags: %s", strings.Join(missing, ", "))
+	}
+	for _, tag := range tags {
+		allowed, ok := p.Allowed[tag.Name]
+		if !ok {
+			continue
+		}
+		found := false
+		for _, value := range allowed {
+			if value == tag.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value %q is not allowed for tag %s, allowed values are %s", tag.Value, tag.Name, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
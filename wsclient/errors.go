@@ -0,0 +1,30 @@
+package wsclient
+
+import "errors"
+
+// ErrNoResult is returned when the server completed a request without sending any rows
+var ErrNoResult = errors.New("No response from server. Check SQL syntax")
+
+// TransportError is a failure of the websocket connection itself, the request may not have reached the server
+type TransportError struct {
+	Op  string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return "Could not " + e.Op + " websocket -> " + e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// ServerError is an error message sent by the server for a request
+type ServerError struct {
+	RequestID string
+	Message   string
+}
+
+func (e *ServerError) Error() string {
+	return e.Message
+}
@@ -1,14 +1,17 @@
 package wsclient
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -17,6 +20,15 @@ import (
 	"github.com/pavi6691/go-boilingdata/models"
 )
 
+var errNotConnected = errors.New("Not connected to WebSocket server")
+var errConnectionClosed = errors.New("connection closed")
+
+// outgoing is a message waiting to be written and the request it belongs to
+type outgoing struct {
+	requestID string
+	message   []byte
+}
+
 // WSSClient represents the WebSocket client.
 type WSSClient struct {
 	URL                 string
@@ -29,9 +41,11 @@ type WSSClient struct {
 	SignedHeader        http.Header
 	Error               string
 	mu                  sync.Mutex
-	queryMessageChannel chan []byte
+	queryMessageChannel chan outgoing
 	isEveythingOK       bool
 	resultsMap          cmap.ConcurrentMap
+	idleDeadline        time.Time
+	inFlight            int64
 }
 
 // NewWSSClient creates a new instance of WSSClient.
@@ -45,7 +59,7 @@ func NewWSSClient(url string, idleTimeoutMinutes time.Duration, signedHeader htt
 		DialOpts:            &websocket.Dialer{},
 		idleTimeoutMinutes:  idleTimeoutMinutes,
 		SignedHeader:        signedHeader,
-		queryMessageChannel: make(chan []byte),
+		queryMessageChannel: make(chan outgoing),
 		isEveythingOK:       true,
 		resultsMap:          cmap.New(),
 	}
@@ -92,11 +106,6 @@ func (wsc *WSSClient) connect() {
 		return
 	}
 	wsc.Conn = conn // Assign the connection to the Conn field
-	if wsc.idleTimeoutMinutes <= 0 {
-		wsc.idleTimeoutMinutes = constants.IdleTimeoutMinutes
-	} else {
-		wsc.idleTimeoutMinutes = wsc.idleTimeoutMinutes * time.Minute
-	}
 	wsc.isEveythingOK = true
 	go wsc.sendMessageAsync()
 	go wsc.receiveMessageAsync()
@@ -106,9 +115,9 @@ func (wsc *WSSClient) connect() {
 
 // SendMessage sends a message over the WebSocket connection.
 func (wsc *WSSClient) SendMessage(message []byte, payload models.Payload) {
-	wsc.resultsMap.Set("error", nil)
+	atomic.AddInt64(&wsc.inFlight, 1)
 	wsc.resultsMap.Set(payload.RequestID, nil)
-	wsc.queryMessageChannel <- message
+	wsc.queryMessageChannel <- outgoing{requestID: payload.RequestID, message: message}
 }
 
 // Close closes the WebSocket connection. perform clean up
@@ -120,21 +129,67 @@ func (wsc *WSSClient) Close() {
 		wsc.Conn.Close()
 		wsc.Conn = nil
 		wsc.idleTimer = nil
-		wsc.resultsMap.Clear()
+		wsc.fail(&TransportError{Op: "read message from", Err: errConnectionClosed})
 		log.Println("Websocket connnection closed")
 	}
 }
 
+// fail ends every request still waiting for its response with err. Requests that already
+// received all parts or failed keep their result.
+func (wsc *WSSClient) fail(err error) {
+	for _, requestID := range wsc.resultsMap.Keys() {
+		absent := false
+		wsc.resultsMap.Upsert(requestID, err, func(exist bool, v interface{}, err interface{}) interface{} {
+			if !exist {
+				absent = true
+				return nil
+			}
+			if _, ok := v.(error); ok {
+				return v
+			}
+			if responses, ok := v.(cmap.ConcurrentMap); ok && isComplete(partsOf(responses)) {
+				return v
+			}
+			return err
+		})
+		if absent {
+			// The waiter returned meanwhile, request ids are not reused
+			wsc.resultsMap.Remove(requestID)
+		}
+	}
+}
+
 func (wsc *WSSClient) IsWebSocketClosed() bool {
 	return wsc.Conn == nil || !wsc.isEveythingOK
 }
 
+// InFlight returns the number of queries sent and still waiting for their response
+func (wsc *WSSClient) InFlight() int {
+	return int(atomic.LoadInt64(&wsc.inFlight))
+}
+
+// IdleTimeoutRemaining returns the time left before the idle connection is closed
+func (wsc *WSSClient) IdleTimeoutRemaining() time.Duration {
+	if wsc.IsWebSocketClosed() {
+		return 0
+	}
+	return time.Until(wsc.idleDeadline)
+}
+
+func (wsc *WSSClient) idleTimeout() time.Duration {
+	if wsc.idleTimeoutMinutes <= 0 {
+		return constants.IdleTimeoutMinutes
+	}
+	return wsc.idleTimeoutMinutes * time.Minute
+}
+
 // resetIdleTimer resets the idle timer.
 func (wsc *WSSClient) resetIdleTimer() {
 	if wsc.idleTimer != nil {
 		wsc.idleTimer.Stop()
 	}
-	wsc.idleTimer = time.AfterFunc(wsc.idleTimeoutMinutes, func() {
+	wsc.idleDeadline = time.Now().Add(wsc.idleTimeout())
+	wsc.idleTimer = time.AfterFunc(wsc.idleTimeout(), func() {
 		log.Println("Idle timeout reached, closing connection")
 		wsc.Close()
 	})
@@ -145,22 +200,26 @@ func (wsc *WSSClient) sendMessageAsync() {
 	defer wsc.Close()
 	for {
 		// Read message from the query message channel
-		message, ok := <-wsc.queryMessageChannel
+		out, ok := <-wsc.queryMessageChannel
 		if !ok || !wsc.isEveythingOK {
 			log.Println("SendMessageAsync process interrupted. No messages will be sent to websocket now onwards.  Action : Reconnect websocket")
+			if ok {
+				wsc.resultsMap.Set(out.requestID, &TransportError{Op: "send message to", Err: errNotConnected})
+			}
 			break
 		}
 		if wsc.Conn == nil {
 			wsc.Error = "Could not send message to websocket -> Not connected to WebSocket server"
-			wsc.resultsMap.Set("error", fmt.Errorf("Could not send message to websocket -> "+"Not connected to WebSocket server"))
+			wsc.resultsMap.Set(out.requestID, &TransportError{Op: "send message to", Err: errNotConnected})
 			return
 		}
-		wsc.idleTimer.Reset(constants.IdleTimeoutMinutes)
+		wsc.idleTimer.Reset(wsc.idleTimeout())
+		wsc.idleDeadline = time.Now().Add(wsc.idleTimeout())
 		wsc.mu.Lock()
-		err := wsc.Conn.WriteMessage(websocket.TextMessage, message)
+		err := wsc.Conn.WriteMessage(websocket.TextMessage, out.message)
 		if err != nil {
 			wsc.isEveythingOK = false
-			wsc.resultsMap.Set("error", fmt.Errorf("Could not send message to websocket -> "+"Not connected to WebSocket server"))
+			wsc.resultsMap.Set(out.requestID, &TransportError{Op: "send message to", Err: err})
 		}
 		wsc.mu.Unlock()
 	}
@@ -172,7 +231,7 @@ func (wsc *WSSClient) receiveMessageAsync() {
 	for {
 		if wsc.Conn == nil {
 			wsc.Error = "Could not receive message from websocket -> Not connected to WebSocket server"
-			wsc.resultsMap.Set("error", fmt.Errorf("Could not recieve message from websocket -> "+"Not connected to WebSocket server"))
+			wsc.fail(&TransportError{Op: "receive message from", Err: errNotConnected})
 			return
 		}
 		if !wsc.isEveythingOK {
@@ -182,150 +241,186 @@ func (wsc *WSSClient) receiveMessageAsync() {
 		_, message, err := wsc.Conn.ReadMessage()
 		if err != nil {
 			wsc.isEveythingOK = false
-			wsc.resultsMap.Set("error", fmt.Errorf("Could not read message from websocket -> ", err.Error()))
+			wsc.fail(&TransportError{Op: "read message from", Err: err})
 		} else if message != nil {
-			var response *models.Response
-			err = json.Unmarshal([]byte(message), &response)
+			response, err := decodeResponse(message)
+			response.Bytes = int64(len(message))
 			if err != nil {
 				log.Println("Error parsing JSON:", err)
-				wsc.resultsMap.Set(response.RequestID, fmt.Errorf("Error parsing JSON: "+err.Error()))
+				// Only fail a request a caller waits for, the id of a message that can't be parsed may be anything
+				if wsc.resultsMap.Has(response.RequestID) {
+					wsc.resultsMap.Set(response.RequestID, &ServerError{RequestID: response.RequestID, Message: "Error parsing JSON: " + err.Error()})
+				}
+				continue
+			}
+			v, ok := wsc.resultsMap.Get(response.RequestID)
+			if !ok {
+				// Late response of a request whose caller stopped waiting
+				continue
 			}
-			if v, ok := wsc.resultsMap.Get(response.RequestID); !ok || v == nil {
+			if response.MessageType == "ERROR" {
+				message := response.Error
+				if message == "" {
+					message = "Query failed"
+				}
+				wsc.resultsMap.Set(response.RequestID, &ServerError{RequestID: response.RequestID, Message: message})
+				continue
+			}
+			if v == nil {
 				var responses = cmap.New()
 				wsc.resultsMap.Set(response.RequestID, responses)
 			}
-			v, _ := wsc.resultsMap.Get(response.RequestID)
-			if response.TotalSubBatches == 0 || response.TotalSubBatches == response.SubBatchSerial {
-				response.Keys = extractKeys(message)
+			v, _ = wsc.resultsMap.Get(response.RequestID)
+			if responses, ok := v.(cmap.ConcurrentMap); ok {
+				responses.Set(partKey(response), response)
 			}
-			v.(cmap.ConcurrentMap).Set(string(response.SubBatchSerial), response)
 		}
 	}
 }
 
-// Function to extract keys from the "data" array
-func extractKeys(jsonData []byte) []string {
-	// Define a struct to hold the "data" array
-	var data struct {
-		Data []json.RawMessage `json:"data"`
-	}
-
-	// Unmarshal the JSON data into the struct
-	err := json.Unmarshal(jsonData, &data)
-	if err != nil {
-		log.Println("Error extracting keys from response data:", err)
-		return nil
+// decodeResponse decodes a websocket message. The rows are decoded with a token level decoder
+// which records the ordered schema of the columns, numbers are kept as json.Number.
+func decodeResponse(message []byte) (*models.Response, error) {
+	var envelope struct {
+		models.Response
+		Data json.RawMessage `json:"data"`
 	}
-
-	// If there's no data, return nil
-	if len(data.Data) == 0 {
-		log.Println("No data found")
-		return nil
+	if err := json.Unmarshal(message, &envelope); err != nil {
+		return &envelope.Response, err
 	}
-
-	// Define an empty map to store the keys of the first entry
-	var firstEntry json.RawMessage
-
-	// Unmarshal the first entry to extract the keys
-	err = json.Unmarshal(data.Data[0], &firstEntry)
+	response := &envelope.Response
+	data, schema, err := models.DecodeRows(envelope.Data)
 	if err != nil {
-		log.Println("Error extracting keys from response data:", err)
-		return nil
+		return response, err
 	}
-	return parse(firstEntry)
+	response.Data = data
+	response.Schema = schema
+	return response, nil
 }
 
-func parse(raw json.RawMessage) []string {
-	// Convert RawMessage to byte slice
-	rawData := []byte(raw)
-
-	var keys []string
+// partKey identifies a part of a response by its batch, split and sub batch serials
+func partKey(response *models.Response) string {
+	return fmt.Sprintf("%d:%d:%d", response.BatchSerial, response.SplitSerial, response.SubBatchSerial)
+}
 
-	// Index keeps track of the position in the JSON byte slice
-	index := 0
+// partsOf returns the parts of a response received so far
+func partsOf(responses cmap.ConcurrentMap) []*models.Response {
+	parts := make([]*models.Response, 0, responses.Count())
+	for item := range responses.IterBuffered() {
+		parts = append(parts, item.Val.(*models.Response))
+	}
+	return parts
+}
 
-	// Loop until the end of the JSON byte slice
-	for index < len(rawData) {
-		// Find the next double quote, which indicates the start of a key
-		keyStart := bytes.IndexByte(rawData[index:], '"')
-		if keyStart == -1 {
-			// If no double quote is found, break the loop
-			break
+// isComplete reports whether all sub batches of all splits of all batches have been received.
+// A zero total means the response was not split at that level.
+func isComplete(parts []*models.Response) bool {
+	type split struct{ batch, split int }
+	subBatches := make(map[split]map[int]bool)
+	expectedSubBatches := make(map[split]int)
+	expectedSplits := make(map[int]int)
+	expectedBatches := 1
+	for _, p := range parts {
+		key := split{p.BatchSerial, p.SplitSerial}
+		if subBatches[key] == nil {
+			subBatches[key] = make(map[int]bool)
 		}
-
-		// Adjust the index to the position of the double quote
-		index += keyStart + 1
-
-		// Find the end of the key by searching for the closing double quote
-		keyEnd := bytes.IndexByte(rawData[index:], '"')
-		if keyEnd == -1 {
-			// If no closing double quote is found, break the loop
-			break
+		subBatches[key][p.SubBatchSerial] = true
+		expectedSubBatches[key] = max(1, p.TotalSubBatches)
+		expectedSplits[p.BatchSerial] = max(1, p.TotalSplitSerials)
+		expectedBatches = max(expectedBatches, p.TotalBatches)
+	}
+	completeSplits := make(map[int]int)
+	for key, subs := range subBatches {
+		if len(subs) >= expectedSubBatches[key] {
+			completeSplits[key.batch]++
 		}
-
-		// Extract the key from the JSON byte slice
-		key := string(rawData[index : index+keyEnd])
-
-		// Check if the key is followed by a colon (":")
-		if index+keyEnd+1 < len(rawData) && string(rawData[index+keyEnd:index+keyEnd+2]) == "\":" {
-			// Append the key to the keys slice
-			keys = append(keys, key)
+	}
+	completeBatches := 0
+	for batch, n := range completeSplits {
+		if n >= expectedSplits[batch] {
+			completeBatches++
 		}
-
-		// Adjust the index to the position after the closing double quote
-		index += keyEnd + 1
 	}
+	return completeBatches >= expectedBatches
+}
 
-	return keys
+// assemble joins the rows of all parts in batch, split and sub batch order and unions their schemas
+func assemble(parts []*models.Response) *models.Response {
+	sort.Slice(parts, func(i, j int) bool {
+		a, b := parts[i], parts[j]
+		if a.BatchSerial != b.BatchSerial {
+			return a.BatchSerial < b.BatchSerial
+		}
+		if a.SplitSerial != b.SplitSerial {
+			return a.SplitSerial < b.SplitSerial
+		}
+		return a.SubBatchSerial < b.SubBatchSerial
+	})
+	data := []map[string]interface{}{}
+	schema := models.NewSchema()
+	cacheInfos := make([]string, 0, len(parts))
+	var bytes int64
+	for _, p := range parts {
+		bytes += p.Bytes
+		data = append(data, p.Data...)
+		schema.Merge(p.Schema)
+		cacheInfos = append(cacheInfos, p.CacheInfo)
+	}
+	finalResponse := *parts[len(parts)-1]
+	finalResponse.Data = data
+	finalResponse.Schema = schema
+	finalResponse.CacheInfos = cacheInfos
+	finalResponse.Bytes = bytes
+	return &finalResponse
 }
 
 func (wsc *WSSClient) GetResponseSync(requestID string) (*models.Response, error) {
-	var temp *models.Response
+	return wsc.GetResponse(context.Background(), requestID)
+}
+
+// GetResponse waits until all sub batches of the request are received or ctx is done
+func (wsc *WSSClient) GetResponse(ctx context.Context, requestID string) (*models.Response, error) {
+	return wsc.WaitResponse(ctx, requestID, nil)
+}
+
+// WaitResponse is GetResponse calling onParts with the parts received so far whenever a part arrives
+func (wsc *WSSClient) WaitResponse(ctx context.Context, requestID string, onParts func(parts []*models.Response)) (*models.Response, error) {
+	defer atomic.AddInt64(&wsc.inFlight, -1)
+	defer wsc.resultsMap.Remove(requestID)
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	received := 0
 	for {
-		if v, ok := wsc.resultsMap.Get("error"); ok {
-			if v != nil {
-				return &models.Response{}, v.(error)
-			}
+		select {
+		case <-ctx.Done():
+			return &models.Response{}, ctx.Err()
+		case <-ticker.C:
+		}
+		responses, ok := wsc.resultsMap.Get(requestID)
+		if !ok {
+			return &models.Response{}, &TransportError{Op: "read message from", Err: errConnectionClosed}
 		}
-		if _, ok := wsc.resultsMap.Get(requestID); !ok {
+		if responses == nil {
 			continue
 		}
-		responses, _ := wsc.resultsMap.Get(requestID)
 		if v, ok := responses.(error); ok {
 			return &models.Response{}, v
 		}
-		if responses == nil {
-			continue
-		}
-		if v, ok := responses.(cmap.ConcurrentMap); ok {
-			if v.Count() > 0 {
-				if temp == nil {
-					for item := range v.IterBuffered() {
-						temp = item.Val.(*models.Response)
-						break
-					}
-				}
-				if len(temp.Data) <= 0 {
-					return &models.Response{}, fmt.Errorf("No response from server. Check SQL syntax")
-				} else if temp.TotalSubBatches == 0 || temp.TotalSubBatches == v.Count() {
-					var data []map[string]interface{}
-					for i := 0; i <= v.Count(); i++ {
-						v, _ := v.Get(string(rune(i)))
-						if v != nil {
-							data = append(data, v.(*models.Response).Data...)
-						}
-					}
-					if v.Count() > 0 {
-						val, _ := v.Get(string(rune(v.Count())))
-						if val == nil {
-							val, _ = v.Get(string(rune(0)))
-						}
-						finalResponse := val.(*models.Response)
-						finalResponse.Data = data
-						return finalResponse, nil
-					}
-				}
+		if v, ok := responses.(cmap.ConcurrentMap); ok && v.Count() > 0 {
+			parts := partsOf(v)
+			if onParts != nil && len(parts) != received {
+				received = len(parts)
+				onParts(parts)
+			}
+			if !isComplete(parts) {
+				continue
+			}
+			finalResponse := assemble(parts)
+			if len(finalResponse.Data) <= 0 {
+				return &models.Response{}, ErrNoResult
 			}
+			return finalResponse, nil
 		}
 	}
 }